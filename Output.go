@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"ray-tracing/film"
+	"ray-tracing/primitives"
+	"ray-tracing/scene"
+	"strings"
+)
+
+// writeOutput saves the image in the format given by the extension of filename.
+// OpenEXR keeps the AOVs as layers of the same file, the other formats write every
+// AOV next to the image as <name>_<aov>.<ext>.
+// Floating point formats store the raw linear radiance, the film is only applied to png.
+// Radiance cannot hold negative values, so its normals are mapped to [0, 1] and its ids are shifted by one.
+func writeOutput(curScene *scene.Scene, filename string, compression film.EXRCompression) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	pixels, aovBuffers := curScene.Output()
+	extension := strings.ToLower(filepath.Ext(filename))
+	switch extension {
+	case ".png":
+		if err := saveFile(filename, func(writer io.Writer) error {
+			return png.Encode(writer, curScene.Film.ToImage(pixels))
+		}); err != nil {
+			return err
+		}
+	case ".hdr":
+		if err := saveFile(filename, func(writer io.Writer) error {
+			return film.WriteRadiance(writer, pixels)
+		}); err != nil {
+			return err
+		}
+	case ".exr":
+		channels := film.ColorChannels("", pixels)
+		for _, aov := range curScene.AOVs {
+			aovChannels, err := aovBuffers.Channels(aov)
+			if err != nil {
+				return err
+			}
+			channels = append(channels, aovChannels...)
+		}
+		return saveFile(filename, func(writer io.Writer) error {
+			return film.WriteEXR(writer, len(pixels), len(pixels[0]), channels, compression)
+		})
+	default:
+		return fmt.Errorf("unsupported output format %q", extension)
+	}
+
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	for _, aov := range curScene.AOVs {
+		aovFilename := fmt.Sprintf("%s_%s%s", base, aov, filepath.Ext(filename))
+		var encode func(writer io.Writer) error
+		if extension == ".png" {
+			result, err := aovBuffers.Image(aov, curScene.Film)
+			if err != nil {
+				return err
+			}
+			encode = func(writer io.Writer) error {
+				return png.Encode(writer, result)
+			}
+		} else {
+			channels, err := aovBuffers.Channels(aov)
+			if err != nil {
+				return err
+			}
+			scale, offset := 1.0, 0.0
+			switch aov {
+			case scene.NormalAOV:
+				scale, offset = 0.5, 0.5
+			case scene.MaterialAOV, scene.ObjectAOV:
+				offset = 1
+			}
+			encode = func(writer io.Writer) error {
+				return film.WriteRadiance(writer, channelColors(channels, scale, offset))
+			}
+		}
+		if err := saveFile(aovFilename, encode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// channelColors packs up to three channels into colours, a single channel is repeated as grey
+func channelColors(channels []film.Channel, scale, offset float64) [][]primitives.Color {
+	pixels := make([][]primitives.Color, len(channels[0].Values))
+	for x := range pixels {
+		pixels[x] = make([]primitives.Color, len(channels[0].Values[x]))
+		for y := range pixels[x] {
+			value := func(i int) float64 {
+				if i >= len(channels) {
+					i = 0
+				}
+				return channels[i].Values[x][y]*scale + offset
+			}
+			pixels[x][y] = primitives.Color{R: value(0), G: value(1), B: value(2)}
+		}
+	}
+	return pixels
+}
+
+func saveFile(filename string, encode func(writer io.Writer) error) error {
+	writer, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := encode(writer); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,91 @@
+package film
+
+import (
+    "fmt"
+    "image"
+    "image/color"
+    "math"
+    "ray-tracing/primitives"
+)
+
+type ToneMapping string
+
+const (
+    // ClampToneMapping cuts everything above 1, it is the default
+    ClampToneMapping    ToneMapping = "clamp"
+    ReinhardToneMapping ToneMapping = "reinhard"
+    ACESToneMapping     ToneMapping = "aces"
+)
+
+// Film turns linear unbounded radiance into displayable values
+type Film struct {
+    // Exposure is measured in stops, every stop doubles the brightness
+    Exposure    float64
+    ToneMapping ToneMapping
+    // Linear skips the sRGB transfer curve, for compositing
+    Linear bool
+}
+
+func (film *Film) Validate() error {
+    switch film.ToneMapping {
+    case "", ClampToneMapping, ReinhardToneMapping, ACESToneMapping:
+        return nil
+    default:
+        return fmt.Errorf("unknown tone mapping %q", film.ToneMapping)
+    }
+}
+
+// Develop applies exposure and tone mapping, the result is linear and lies in [0, 1]
+func (film *Film) Develop(radiance primitives.Color) primitives.Color {
+    exposed := radiance.Mult(math.Pow(2, film.Exposure))
+    return primitives.Color{
+        R: film.toneMap(exposed.R),
+        G: film.toneMap(exposed.G),
+        B: film.toneMap(exposed.B),
+    }
+}
+
+func (film *Film) toneMap(value float64) float64 {
+    value = math.Max(value, 0)
+    switch film.ToneMapping {
+    case ReinhardToneMapping:
+        value = value / (1 + value)
+    case ACESToneMapping:
+        // Narkowicz fit of the ACES filmic curve
+        value = (value * (2.51*value + 0.03)) / (value*(2.43*value+0.59) + 0.14)
+    }
+    return primitives.Clamp(0, 1, value)
+}
+
+// Encode applies the output transfer curve to a developed colour
+func (film *Film) Encode(developed primitives.Color) primitives.Color {
+    if film.Linear {
+        return developed
+    }
+    return developed.Encode(primitives.SRGBColorSpace)
+}
+
+func quantize(value float64) uint8 {
+    return uint8(math.Round(255 * primitives.Clamp(0, 1, value)))
+}
+
+// ToImage develops the pixels, stored as columns, into an 8-bit image
+func (film *Film) ToImage(pixels [][]primitives.Color) *image.RGBA {
+    width, height := len(pixels), 0
+    if width > 0 {
+        height = len(pixels[0])
+    }
+    result := image.NewRGBA(image.Rect(0, 0, width, height))
+    for x := 0; x < width; x++ {
+        for y := 0; y < height; y++ {
+            developed := film.Encode(film.Develop(pixels[x][y]))
+            result.SetRGBA(x, y, color.RGBA{
+                R: quantize(developed.R),
+                G: quantize(developed.G),
+                B: quantize(developed.B),
+                A: 255,
+            })
+        }
+    }
+    return result
+}
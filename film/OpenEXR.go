@@ -0,0 +1,188 @@
+package film
+
+import (
+    "bytes"
+    "compress/zlib"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "math"
+    "ray-tracing/primitives"
+    "sort"
+)
+
+type EXRCompression string
+
+const (
+    NoEXRCompression EXRCompression = "none"
+    // ZipEXRCompression deflates blocks of 16 scanlines, it is the default
+    ZipEXRCompression EXRCompression = "zip"
+)
+
+const EXR_MAGIC int32 = 20000630
+const EXR_FLOAT_PIXELS int32 = 2
+const EXR_ZIP_SCANLINES int = 16
+
+// compression codes of the OpenEXR header
+const (
+    exrNoCompression byte = 0
+    exrZipCompression byte = 3
+)
+
+// Channel is a named plane of values stored as columns, layers are expressed
+// with dotted names such as depth.Z
+type Channel struct {
+    Name   string
+    Values [][]float64
+}
+
+func ValidateEXRCompression(compression EXRCompression) error {
+    switch compression {
+    case "", NoEXRCompression, ZipEXRCompression:
+        return nil
+    default:
+        return fmt.Errorf("unknown OpenEXR compression %q", compression)
+    }
+}
+
+// ColorChannels splits the pixels into the R, G and B channels of the layer, the
+// unnamed layer is the main image
+func ColorChannels(layer string, pixels [][]primitives.Color) []Channel {
+    prefix := ""
+    if layer != "" {
+        prefix = layer + "."
+    }
+    channels := []Channel{
+        {prefix + "R", make([][]float64, len(pixels))},
+        {prefix + "G", make([][]float64, len(pixels))},
+        {prefix + "B", make([][]float64, len(pixels))},
+    }
+    for x := range pixels {
+        for i := range channels {
+            channels[i].Values[x] = make([]float64, len(pixels[x]))
+        }
+        for y, color := range pixels[x] {
+            channels[0].Values[x][y] = color.R
+            channels[1].Values[x][y] = color.G
+            channels[2].Values[x][y] = color.B
+        }
+    }
+    return channels
+}
+
+// WriteEXR stores the channels as a single part scanline OpenEXR image with 32-bit float pixels
+func WriteEXR(writer io.Writer, width, height int, channels []Channel, compression EXRCompression) error {
+    if err := ValidateEXRCompression(compression); err != nil {
+        return err
+    }
+    channels = append([]Channel(nil), channels...)
+    sort.Slice(channels, func(i, j int) bool {
+        return channels[i].Name < channels[j].Name
+    })
+
+    linesPerBlock, compressionCode := EXR_ZIP_SCANLINES, exrZipCompression
+    if compression == NoEXRCompression {
+        linesPerBlock, compressionCode = 1, exrNoCompression
+    }
+
+    var output bytes.Buffer
+    write := func(values ...interface{}) {
+        for _, value := range values {
+            _ = binary.Write(&output, binary.LittleEndian, value)
+        }
+    }
+    attribute := func(name, kind string, size int) {
+        output.WriteString(name + "\x00" + kind + "\x00")
+        write(int32(size))
+    }
+    write(EXR_MAGIC, int32(2))
+
+    channelListSize := 1
+    for _, channel := range channels {
+        channelListSize += len(channel.Name) + 1 + 16
+    }
+    attribute("channels", "chlist", channelListSize)
+    for _, channel := range channels {
+        output.WriteString(channel.Name + "\x00")
+        write(EXR_FLOAT_PIXELS, [4]byte{}, int32(1), int32(1))
+    }
+    output.WriteByte(0)
+    attribute("compression", "compression", 1)
+    output.WriteByte(compressionCode)
+    attribute("dataWindow", "box2i", 16)
+    write(int32(0), int32(0), int32(width-1), int32(height-1))
+    attribute("displayWindow", "box2i", 16)
+    write(int32(0), int32(0), int32(width-1), int32(height-1))
+    attribute("lineOrder", "lineOrder", 1)
+    output.WriteByte(0)
+    attribute("pixelAspectRatio", "float", 4)
+    write(float32(1))
+    attribute("screenWindowCenter", "v2f", 8)
+    write(float32(0), float32(0))
+    attribute("screenWindowWidth", "float", 4)
+    write(float32(1))
+    output.WriteByte(0)
+
+    var blocks [][]byte
+    for y0 := 0; y0 < height; y0 += linesPerBlock {
+        var raw bytes.Buffer
+        for y := y0; y < y0+linesPerBlock && y < height; y++ {
+            for _, channel := range channels {
+                for x := 0; x < width; x++ {
+                    _ = binary.Write(&raw, binary.LittleEndian, math.Float32bits(float32(channel.Values[x][y])))
+                }
+            }
+        }
+        data := raw.Bytes()
+        if compression != NoEXRCompression {
+            compressed, err := zipBlock(data)
+            if err != nil {
+                return err
+            }
+            // blocks that do not shrink are stored as they are
+            if len(compressed) < len(data) {
+                data = compressed
+            }
+        }
+        blocks = append(blocks, data)
+    }
+
+    offset := uint64(output.Len() + 8*len(blocks))
+    for _, block := range blocks {
+        write(offset)
+        offset += uint64(8 + len(block))
+    }
+    for i, block := range blocks {
+        write(int32(i*linesPerBlock), int32(len(block)))
+        output.Write(block)
+    }
+    _, err := output.WriteTo(writer)
+    return err
+}
+
+// zipBlock interleaves the bytes, applies the delta predictor and deflates the block
+// the way OpenEXR ZIP compression expects
+func zipBlock(data []byte) ([]byte, error) {
+    reordered := make([]byte, len(data))
+    half := (len(data) + 1) / 2
+    for i := range data {
+        if i%2 == 0 {
+            reordered[i/2] = data[i]
+        } else {
+            reordered[half+i/2] = data[i]
+        }
+    }
+    for i := len(reordered) - 1; i > 0; i-- {
+        reordered[i] = byte(int(reordered[i]) - int(reordered[i-1]) + 128)
+    }
+
+    var compressed bytes.Buffer
+    deflater := zlib.NewWriter(&compressed)
+    if _, err := deflater.Write(reordered); err != nil {
+        return nil, err
+    }
+    if err := deflater.Close(); err != nil {
+        return nil, err
+    }
+    return compressed.Bytes(), nil
+}
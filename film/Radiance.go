@@ -0,0 +1,41 @@
+package film
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "math"
+    "ray-tracing/primitives"
+)
+
+// WriteRadiance stores the pixels, given as columns, as a flat Radiance RGBE image
+func WriteRadiance(writer io.Writer, pixels [][]primitives.Color) error {
+    width, height := len(pixels), 0
+    if width > 0 {
+        height = len(pixels[0])
+    }
+    output := bufio.NewWriter(writer)
+    if _, err := fmt.Fprintf(output, "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", height, width); err != nil {
+        return err
+    }
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            rgbe := toRGBE(pixels[x][y])
+            if _, err := output.Write(rgbe[:]); err != nil {
+                return err
+            }
+        }
+    }
+    return output.Flush()
+}
+
+func toRGBE(color primitives.Color) [4]byte {
+    r, g, b := math.Max(color.R, 0), math.Max(color.G, 0), math.Max(color.B, 0)
+    value := math.Max(r, math.Max(g, b))
+    if value < 1e-32 {
+        return [4]byte{}
+    }
+    mantissa, exponent := math.Frexp(value)
+    scale := mantissa * 256 / value
+    return [4]byte{byte(r * scale), byte(g * scale), byte(b * scale), byte(exponent + 128)}
+}
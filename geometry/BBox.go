@@ -4,6 +4,7 @@ import (
     "math"
     "ray-tracing/primitives"
     "sort"
+    "strconv"
 )
 
 type BBox struct {
@@ -29,9 +30,19 @@ func (bbox *BBox) Contains(point primitives.Vector) bool {
     return bbox.Left.LessEqual(point) && bbox.Right.GreaterEqual(point)
 }
 
+// IsBounded is false for the infinite boxes of unbounded objects, those cannot be put in a KD-tree
+func (bbox *BBox) IsBounded() bool {
+    for _, value := range []float64{bbox.Left.X, bbox.Left.Y, bbox.Left.Z, bbox.Right.X, bbox.Right.Y, bbox.Right.Z} {
+        if math.IsInf(value, 0) || math.IsNaN(value) {
+            return false
+        }
+    }
+    return true
+}
+
 func (bbox *BBox) Split(axisNumber int, value float64) [2]*BBox {
     if axisNumber > 2 {
-        panic("Wrong axis " + string(axisNumber))
+        panic("Wrong axis " + strconv.Itoa(axisNumber))
     }
     newLeft := bbox.Left
     newRight := bbox.Right
@@ -63,38 +74,38 @@ func (bbox *BBox) CreatePlaneAndIntersect(ray *Ray, p1, p2, p3 primitives.Vector
     if !bbox.Contains(ray.Begin.Add(ray.Direction.Mult(coef))) {
         return RayCoefIntersection{}
     }
-    return RayCoefIntersection{true, coef}
+    return NewRayCoefIntersection(coef)
 }
 
 func (bbox *BBox) Intersect(ray *Ray) [2]RayCoefIntersection {
     var pu, pd, pl, pr, pn, pf RayCoefIntersection
 
     pd = bbox.CreatePlaneAndIntersect(ray,
-        primitives.Vector{bbox.Left.X, bbox.Left.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Right.X, bbox.Left.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Left.X, bbox.Right.Y, bbox.Left.Z})
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Left.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Left.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Right.Y, Z: bbox.Left.Z})
     pu = bbox.CreatePlaneAndIntersect(ray,
-        primitives.Vector{bbox.Left.X, bbox.Left.Y, bbox.Right.Z},
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Left.Y, Z: bbox.Right.Z},
         primitives.Vector{X: bbox.Right.X, Y: bbox.Left.Y, Z: bbox.Right.Z},
-        primitives.Vector{bbox.Left.X, bbox.Right.Y, bbox.Right.Z})
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Right.Y, Z: bbox.Right.Z})
 
     pl = bbox.CreatePlaneAndIntersect(ray,
-        primitives.Vector{bbox.Left.X, bbox.Left.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Left.X, bbox.Right.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Left.X, bbox.Right.Y, bbox.Right.Z})
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Left.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Right.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Right.Y, Z: bbox.Right.Z})
     pr = bbox.CreatePlaneAndIntersect(ray,
-        primitives.Vector{bbox.Right.X, bbox.Left.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Right.X, bbox.Right.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Right.X, bbox.Right.Y, bbox.Right.Z})
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Left.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Right.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Right.Y, Z: bbox.Right.Z})
 
     pn = bbox.CreatePlaneAndIntersect(ray,
-        primitives.Vector{bbox.Left.X, bbox.Left.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Right.X, bbox.Left.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Right.X, bbox.Left.Y, bbox.Right.Z})
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Left.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Left.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Left.Y, Z: bbox.Right.Z})
     pf = bbox.CreatePlaneAndIntersect(ray,
-        primitives.Vector{bbox.Left.X, bbox.Right.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Right.X, bbox.Right.Y, bbox.Left.Z},
-        primitives.Vector{bbox.Right.X, bbox.Right.Y, bbox.Right.Z})
+        primitives.Vector{X: bbox.Left.X, Y: bbox.Right.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Right.Y, Z: bbox.Left.Z},
+        primitives.Vector{X: bbox.Right.X, Y: bbox.Right.Y, Z: bbox.Right.Z})
 
     coefs := make([]float64, 0, 6)
     if pd.HasIntersection {
@@ -130,7 +141,7 @@ func (bbox *BBox) Intersect(ray *Ray) [2]RayCoefIntersection {
             break
         }
     }
-    return [2]RayCoefIntersection{{true, firstCoef}, {true, lastCoef}}
+    return [2]RayCoefIntersection{NewRayCoefIntersection(firstCoef), NewRayCoefIntersection(lastCoef)}
 }
 
 func (bbox *BBox) GetMin(axis int) float64 {
@@ -0,0 +1,97 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+// Box is a cuboid centred at Center, its edges follow the orthonormal axes of its frame
+type Box struct {
+    ObjectId
+    Center primitives.Vector
+    // HalfSize is half of the box extent along each of its axes
+    HalfSize primitives.Vector
+    Material *materials.Material
+    frame    frame
+}
+
+// NewBox creates an axis aligned box of the given size
+func NewBox(center, size primitives.Vector, material *materials.Material) *Box {
+    return NewOrientedBox(center, size, [3]primitives.Vector{{X: 1}, {Y: 1}, {Z: 1}}, material)
+}
+
+// NewOrientedBox creates a box whose edges follow axes, axes have to be orthonormal
+func NewOrientedBox(center, size primitives.Vector, axes [3]primitives.Vector, material *materials.Material) *Box {
+    return &Box{Center: center, HalfSize: size.Mult(0.5), Material: material, frame: frame{center, axes}}
+}
+
+// getFace returns the axis of the face containing a local point and the side of the box it is on
+func (b *Box) getFace(local primitives.Vector) (int, float64) {
+    point, half := components(local), components(b.HalfSize)
+    face, distance := 0, math.Inf(1)
+    for axis := 0; axis < 3; axis++ {
+        if axisDistance := math.Abs(half[axis] - math.Abs(point[axis])) / half[axis]; axisDistance < distance {
+            face, distance = axis, axisDistance
+        }
+    }
+    return face, math.Copysign(1, point[face])
+}
+
+func (b *Box) GetNormal(hit *Intersection) primitives.Vector {
+    face, side := b.getFace(b.frame.toLocal(hit.Point))
+    return b.frame.axes[face].Mult(side)
+}
+
+// GetTexturePoint stretches the whole texture over every face
+func (b *Box) GetTexturePoint(hit *Intersection) primitives.Vector {
+    local := b.frame.toLocal(hit.Point)
+    face, _ := b.getFace(local)
+    point, half := components(local), components(b.HalfSize)
+    u, v := (face + 1) % 3, (face + 2) % 3
+    return primitives.Vector{
+        X: (point[u] / half[u] + 1) / 2,
+        Y: (point[v] / half[v] + 1) / 2,
+    }
+}
+
+func (b *Box) GetBoundingBox() *BBox {
+    half := components(b.HalfSize)
+    var extent primitives.Vector
+    for axis := 0; axis < 3; axis++ {
+        direction := b.frame.axes[axis]
+        extent = extent.Add(primitives.Vector{
+            X: math.Abs(direction.X), Y: math.Abs(direction.Y), Z: math.Abs(direction.Z),
+        }.Mult(half[axis]))
+    }
+    return &BBox{b.Center.Sub(extent), b.Center.Add(extent)}
+}
+
+// Intersect clips the ray with the three slabs of the box in its frame
+func (b *Box) Intersect(ray *Ray) RayCoefIntersection {
+    localBegin, localDirection := b.frame.rayToLocal(ray)
+    begin, direction, half := components(localBegin), components(localDirection), components(b.HalfSize)
+    near, far := math.Inf(-1), math.Inf(1)
+    for axis := 0; axis < 3; axis++ {
+        if direction[axis] == 0 {
+            if math.Abs(begin[axis]) > half[axis] {
+                return RayCoefIntersection{}
+            }
+            continue
+        }
+        first := (-half[axis] - begin[axis]) / direction[axis]
+        second := (half[axis] - begin[axis]) / direction[axis]
+        near, far = math.Max(near, math.Min(first, second)), math.Min(far, math.Max(first, second))
+    }
+    if near > far {
+        return RayCoefIntersection{}
+    }
+    if coef, ok := nearestRoot(near, far); ok {
+        return NewRayCoefIntersection(coef)
+    }
+    return RayCoefIntersection{}
+}
+
+func (b *Box) GetMaterial() *materials.Material {
+    return b.Material
+}
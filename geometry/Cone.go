@@ -0,0 +1,97 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+// Cone is closed by its base, Base is the centre of the base and the apex is Height along Axis
+type Cone struct {
+    ObjectId
+    Base, Axis     primitives.Vector
+    Radius, Height float64
+    Material       *materials.Material
+    frame          frame
+}
+
+func NewCone(base, axis primitives.Vector, radius, height float64, material *materials.Material) *Cone {
+    axis = axis.Norm()
+    return &Cone{Base: base, Axis: axis, Radius: radius, Height: height, Material: material,
+        frame: newFrame(base, axis)}
+}
+
+// getSlope is the radius lost per unit of height
+func (c *Cone) getSlope() float64 {
+    return c.Radius / c.Height
+}
+
+// getSurface picks the side or the base, whichever is nearer to a local point
+func (c *Cone) getSurface(local primitives.Vector) surface {
+    // distance to the side measured across the slanted surface
+    slope := c.getSlope()
+    side := math.Abs(math.Hypot(local.X, local.Y) - slope * (c.Height - local.Z)) / math.Sqrt(1 + slope * slope)
+    if math.Abs(local.Z) < side {
+        return bottomSurface
+    }
+    return sideSurface
+}
+
+func (c *Cone) GetNormal(hit *Intersection) primitives.Vector {
+    local := c.frame.toLocal(hit.Point)
+    if c.getSurface(local) == bottomSurface {
+        return c.Axis.Mult(-1)
+    }
+    // gradient of x^2 + y^2 - (slope * (height - z))^2
+    slope := c.getSlope()
+    normal := primitives.Vector{X: local.X, Y: local.Y, Z: slope * slope * (c.Height - local.Z)}
+    if primitives.Equal(normal.Length(), 0) {
+        // the apex
+        return c.Axis
+    }
+    return c.frame.directionToWorld(normal).Norm()
+}
+
+// GetTexturePoint wraps the texture once around the side with v going up to the apex,
+// the base is mapped like a disk
+func (c *Cone) GetTexturePoint(hit *Intersection) primitives.Vector {
+    local := c.frame.toLocal(hit.Point)
+    if c.getSurface(local) == bottomSurface {
+        return getPolarTexturePoint(local, c.Radius)
+    }
+    return primitives.Vector{X: getAngleTextureCoord(local), Y: local.Z / c.Height}
+}
+
+func (c *Cone) GetBoundingBox() *BBox {
+    bbox := discBoundingBox(c.Base, c.Axis, c.Radius)
+    apex := c.Base.Add(c.Axis.Mult(c.Height))
+    bbox.Expand(&BBox{apex, apex})
+    return bbox
+}
+
+func (c *Cone) Intersect(ray *Ray) RayCoefIntersection {
+    begin, direction := c.frame.rayToLocal(ray)
+    slope2 := c.getSlope() * c.getSlope()
+    // height left to the apex at the ray origin
+    remaining := c.Height - begin.Z
+    candidates := solveQuadratic(
+        direction.X * direction.X + direction.Y * direction.Y - slope2 * direction.Z * direction.Z,
+        2 * (begin.X * direction.X + begin.Y * direction.Y + slope2 * remaining * direction.Z),
+        begin.X * begin.X + begin.Y * begin.Y - slope2 * remaining * remaining)
+    roots := candidates[:0]
+    for _, root := range candidates {
+        // the quadric is a double cone, only the nappe between the base and the apex is kept
+        if z := begin.Z + root * direction.Z; z >= 0 && z <= c.Height {
+            roots = append(roots, root)
+        }
+    }
+    roots = append(roots, intersectCap(begin, direction, 0, c.Radius)...)
+    if coef, ok := nearestRoot(roots...); ok {
+        return NewRayCoefIntersection(coef)
+    }
+    return RayCoefIntersection{}
+}
+
+func (c *Cone) GetMaterial() *materials.Material {
+    return c.Material
+}
@@ -0,0 +1,109 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+// Cylinder is closed by two caps, Base is the centre of the bottom one and Axis points to the top
+type Cylinder struct {
+    ObjectId
+    Base, Axis     primitives.Vector
+    Radius, Height float64
+    Material       *materials.Material
+    frame          frame
+}
+
+func NewCylinder(base, axis primitives.Vector, radius, height float64, material *materials.Material) *Cylinder {
+    axis = axis.Norm()
+    return &Cylinder{Base: base, Axis: axis, Radius: radius, Height: height, Material: material,
+        frame: newFrame(base, axis)}
+}
+
+// surface tells which part of a capped shape a hit is on
+type surface int8
+
+const (
+    sideSurface surface = iota
+    bottomSurface
+    topSurface
+)
+
+// getSurface picks the part of the cylinder nearest to a local point
+func (c *Cylinder) getSurface(local primitives.Vector) surface {
+    side := math.Abs(math.Hypot(local.X, local.Y) - c.Radius)
+    bottom, top := math.Abs(local.Z), math.Abs(local.Z - c.Height)
+    if bottom < side && bottom <= top {
+        return bottomSurface
+    }
+    if top < side {
+        return topSurface
+    }
+    return sideSurface
+}
+
+func (c *Cylinder) GetNormal(hit *Intersection) primitives.Vector {
+    local := c.frame.toLocal(hit.Point)
+    switch c.getSurface(local) {
+    case bottomSurface:
+        return c.Axis.Mult(-1)
+    case topSurface:
+        return c.Axis
+    default:
+        return c.frame.directionToWorld(primitives.Vector{X: local.X, Y: local.Y}).Norm()
+    }
+}
+
+// GetTexturePoint wraps the texture once around the side with v going up the axis,
+// the caps are mapped like disks
+func (c *Cylinder) GetTexturePoint(hit *Intersection) primitives.Vector {
+    local := c.frame.toLocal(hit.Point)
+    if c.getSurface(local) != sideSurface {
+        return getPolarTexturePoint(local, c.Radius)
+    }
+    return primitives.Vector{X: getAngleTextureCoord(local), Y: local.Z / c.Height}
+}
+
+func (c *Cylinder) GetBoundingBox() *BBox {
+    bbox := discBoundingBox(c.Base, c.Axis, c.Radius)
+    bbox.Expand(discBoundingBox(c.Base.Add(c.Axis.Mult(c.Height)), c.Axis, c.Radius))
+    return bbox
+}
+
+func (c *Cylinder) Intersect(ray *Ray) RayCoefIntersection {
+    begin, direction := c.frame.rayToLocal(ray)
+    candidates := solveQuadratic(
+        direction.X * direction.X + direction.Y * direction.Y,
+        2 * (begin.X * direction.X + begin.Y * direction.Y),
+        begin.X * begin.X + begin.Y * begin.Y - c.Radius * c.Radius)
+    roots := candidates[:0]
+    for _, root := range candidates {
+        if z := begin.Z + root * direction.Z; z >= 0 && z <= c.Height {
+            roots = append(roots, root)
+        }
+    }
+    roots = append(roots, intersectCap(begin, direction, 0, c.Radius)...)
+    roots = append(roots, intersectCap(begin, direction, c.Height, c.Radius)...)
+    if coef, ok := nearestRoot(roots...); ok {
+        return NewRayCoefIntersection(coef)
+    }
+    return RayCoefIntersection{}
+}
+
+func (c *Cylinder) GetMaterial() *materials.Material {
+    return c.Material
+}
+
+// intersectCap hits a local ray with the disk of radius perpendicular to the Z axis at height
+func intersectCap(begin, direction primitives.Vector, height, radius float64) []float64 {
+    if direction.Z == 0 {
+        return nil
+    }
+    coef := (height - begin.Z) / direction.Z
+    x, y := begin.X + coef * direction.X, begin.Y + coef * direction.Y
+    if x * x + y * y > radius * radius {
+        return nil
+    }
+    return []float64{coef}
+}
@@ -0,0 +1,59 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+type Disk struct {
+    ObjectId
+    Center, Normal primitives.Vector
+    Radius         float64
+    Material       *materials.Material
+    frame          frame
+}
+
+func NewDisk(center, normal primitives.Vector, radius float64, material *materials.Material) *Disk {
+    normal = normal.Norm()
+    return &Disk{Center: center, Normal: normal, Radius: radius, Material: material, frame: newFrame(center, normal)}
+}
+
+func (d *Disk) GetNormal(hit *Intersection) primitives.Vector {
+    return d.Normal
+}
+
+// GetTexturePoint maps the angle around the centre to u and the distance from it to v
+func (d *Disk) GetTexturePoint(hit *Intersection) primitives.Vector {
+    return getPolarTexturePoint(d.frame.toLocal(hit.Point), d.Radius)
+}
+
+func (d *Disk) GetBoundingBox() *BBox {
+    return discBoundingBox(d.Center, d.Normal, d.Radius)
+}
+
+func (d *Disk) Intersect(ray *Ray) RayCoefIntersection {
+    coef, ok := intersectPlane(ray, d.Center, d.Normal)
+    if !ok || ray.Begin.Add(ray.Direction.Mult(coef)).Sub(d.Center).SqrLength() > d.Radius * d.Radius {
+        return RayCoefIntersection{}
+    }
+    return NewRayCoefIntersection(coef)
+}
+
+func (d *Disk) GetMaterial() *materials.Material {
+    return d.Material
+}
+
+// getPolarTexturePoint maps the angle of a local point around the Z axis to u and its distance
+// from the axis divided by radius to v
+func getPolarTexturePoint(local primitives.Vector, radius float64) primitives.Vector {
+    return primitives.Vector{
+        X: getAngleTextureCoord(local),
+        Y: math.Hypot(local.X, local.Y) / radius,
+    }
+}
+
+// getAngleTextureCoord maps the angle of a local point around the Z axis to [0, 1]
+func getAngleTextureCoord(local primitives.Vector) float64 {
+    return 0.5 + math.Atan2(local.Y, local.X) / (2 * math.Pi)
+}
@@ -0,0 +1,50 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/primitives"
+)
+
+// frame is an orthonormal basis placed at origin, analytic shapes are intersected in it
+type frame struct {
+    origin primitives.Vector
+    axes   [3]primitives.Vector
+}
+
+// newFrame builds a right-handed frame whose third axis is the unit vector axis
+func newFrame(origin, axis primitives.Vector) frame {
+    u, v := axis.Basis()
+    return frame{origin, [3]primitives.Vector{u, v, axis}}
+}
+
+func (f *frame) directionToLocal(direction primitives.Vector) primitives.Vector {
+    return primitives.Vector{X: direction.Dot(f.axes[0]), Y: direction.Dot(f.axes[1]), Z: direction.Dot(f.axes[2])}
+}
+
+func (f *frame) toLocal(point primitives.Vector) primitives.Vector {
+    return f.directionToLocal(point.Sub(f.origin))
+}
+
+func (f *frame) directionToWorld(direction primitives.Vector) primitives.Vector {
+    return f.axes[0].Mult(direction.X).Add(f.axes[1].Mult(direction.Y)).Add(f.axes[2].Mult(direction.Z))
+}
+
+// rayToLocal returns the origin and the direction of ray in the frame
+func (f *frame) rayToLocal(ray *Ray) (primitives.Vector, primitives.Vector) {
+    return f.toLocal(ray.Begin), f.directionToLocal(ray.Direction)
+}
+
+func components(v primitives.Vector) [3]float64 {
+    return [3]float64{v.X, v.Y, v.Z}
+}
+
+// discBoundingBox bounds a disc of radius around center lying in the plane with the unit normal
+func discBoundingBox(center, normal primitives.Vector, radius float64) *BBox {
+    n := components(normal)
+    var extent [3]float64
+    for i := range extent {
+        extent[i] = radius * math.Sqrt(math.Max(0, 1 - n[i] * n[i]))
+    }
+    offset := primitives.Vector{X: extent[0], Y: extent[1], Z: extent[2]}
+    return &BBox{center.Sub(offset), center.Add(offset)}
+}
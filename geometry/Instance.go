@@ -0,0 +1,110 @@
+package geometry
+
+import (
+    "fmt"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+// IMesh is a group of objects sharing one acceleration structure, it is intersected in its own space
+type IMesh interface {
+    Intersect(ray *Ray) Intersection
+    GetBoundingBox() *BBox
+}
+
+// Instance places a shared mesh in the scene, many instances of a mesh only keep their transforms
+type Instance struct {
+    ObjectId
+    Mesh IMesh
+    // Material replaces the materials of the mesh when set
+    Material *materials.Material
+    // MeshObjectIds reports the object ids of the mesh objects instead of the id of the instance
+    MeshObjectIds bool
+
+    transform transform
+    bbox      *BBox
+}
+
+// transformedHit is the object reported for a hit on an instance or a moving object,
+// it remembers the hit in the local space and turns its normal into the world
+type transformedHit struct {
+    owner         IGeometryObject
+    local         Intersection
+    normalToWorld primitives.Matrix4
+    objectId      int
+}
+
+func NewInstance(mesh IMesh, transform primitives.Matrix4, material *materials.Material) (*Instance, error) {
+    return NewMovingInstance(mesh, transform, transform, material)
+}
+
+// NewMovingInstance creates an instance moving from the start transform at time 0 to the end one at time 1
+func NewMovingInstance(mesh IMesh, start, end primitives.Matrix4, material *materials.Material) (*Instance, error) {
+    tr, err := newTransform(start, end)
+    if err != nil {
+        return nil, fmt.Errorf("instance %w", err)
+    }
+    return &Instance{Mesh: mesh, Material: material, transform: tr, bbox: tr.getBoundingBox(mesh.GetBoundingBox())}, nil
+}
+
+func (instance *Instance) GetNormal(hit *Intersection) primitives.Vector {
+    return hit.Coefficient.Object.GetNormal(hit)
+}
+
+func (instance *Instance) GetTexturePoint(hit *Intersection) primitives.Vector {
+    return hit.Coefficient.Object.GetTexturePoint(hit)
+}
+
+func (instance *Instance) GetBoundingBox() *BBox {
+    return instance.bbox
+}
+
+// Intersect moves the ray into the mesh space without normalising its direction,
+// so the coefficient of the hit is the same in both spaces
+func (instance *Instance) Intersect(ray *Ray) RayCoefIntersection {
+    localRay, normalToWorld := instance.transform.toLocalRay(ray)
+    local := instance.Mesh.Intersect(&localRay)
+    if !local.Coefficient.HasIntersection {
+        return RayCoefIntersection{}
+    }
+    result := local.Coefficient
+    hit := &transformedHit{owner: instance, local: local, normalToWorld: normalToWorld, objectId: instance.GetObjectId()}
+    if instance.MeshObjectIds {
+        hit.objectId = local.Object.GetObjectId()
+    }
+    result.Object = hit
+    return result
+}
+
+func (instance *Instance) GetMaterial() *materials.Material {
+    return instance.Material
+}
+
+func (hit *transformedHit) GetNormal(*Intersection) primitives.Vector {
+    normal := hit.local.Object.GetNormal(&hit.local)
+    return hit.normalToWorld.TransformDirection(normal).Norm()
+}
+
+func (hit *transformedHit) GetTexturePoint(*Intersection) primitives.Vector {
+    return hit.local.Object.GetTexturePoint(&hit.local)
+}
+
+func (hit *transformedHit) GetBoundingBox() *BBox {
+    return hit.owner.GetBoundingBox()
+}
+
+func (hit *transformedHit) Intersect(ray *Ray) RayCoefIntersection {
+    return hit.owner.Intersect(ray)
+}
+
+// GetMaterial prefers the material of the owner, instances use it to replace the materials of their mesh
+func (hit *transformedHit) GetMaterial() *materials.Material {
+    if material := hit.owner.GetMaterial(); material != nil {
+        return material
+    }
+    return hit.local.Object.GetMaterial()
+}
+
+func (hit *transformedHit) GetObjectId() int {
+    return hit.objectId
+}
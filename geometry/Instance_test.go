@@ -0,0 +1,132 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/primitives"
+    "testing"
+)
+
+// objectMesh makes a single object usable as the mesh of an instance
+type objectMesh struct {
+    object IGeometryObject
+}
+
+func (mesh objectMesh) Intersect(ray *Ray) Intersection {
+    hit, _ := castAt(mesh.object, ray)
+    return hit
+}
+
+func (mesh objectMesh) GetBoundingBox() *BBox {
+    return mesh.object.GetBoundingBox()
+}
+
+type instanceTest struct {
+    name       string
+    begin, end primitives.Vector
+    coef       float64
+    normal     primitives.Vector
+}
+
+func checkInstanceHits(t *testing.T, obj IGeometryObject, tests []instanceTest) {
+    t.Helper()
+    for _, test := range tests {
+        ray := NewRay(test.begin, test.end)
+        hit, ok := castAt(obj, ray)
+        if !ok {
+            t.Errorf("%s: no hit", test.name)
+            continue
+        }
+        if !primitives.Equal(hit.Coefficient.IntersectionCoef, test.coef) {
+            t.Errorf("%s: coef = %v, want %v", test.name, hit.Coefficient.IntersectionCoef, test.coef)
+        }
+        if !vectorsClose(hit.Point, test.end) {
+            t.Errorf("%s: point = %v, want %v", test.name, hit.Point, test.end)
+        }
+        if normal := hit.Object.GetNormal(&hit); !vectorsClose(normal, test.normal) {
+            t.Errorf("%s: normal = %v, want %v", test.name, normal, test.normal)
+        }
+        if !obj.GetBoundingBox().Contains(hit.Point) {
+            t.Errorf("%s: hit %v lies outside the bounding box %v", test.name, hit.Point, *obj.GetBoundingBox())
+        }
+    }
+}
+
+// TestInstanceSphere stretches a unit sphere into an ellipsoid, the local ray direction is then
+// not normalised and the normals need the inverse transpose of the transform
+func TestInstanceSphere(t *testing.T) {
+    transform := primitives.Translation(primitives.Vector{Z: 5}).
+        Mult(primitives.RotationZ(math.Pi / 2)).
+        Mult(primitives.Scaling(primitives.Vector{X: 2, Y: 1, Z: 1}))
+    instance, err := NewInstance(objectMesh{NewSphere(primitives.Vector{}, 1, nil)}, transform, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // before the rotation the ellipsoid point (2 cos a, sin a) has the normal (cos a / 2, sin a)
+    angle := math.Pi / 4
+    local := primitives.Vector{X: 2 * math.Cos(angle), Y: math.Sin(angle)}
+    localNormal := primitives.Vector{X: math.Cos(angle) / 2, Y: math.Sin(angle)}.Norm()
+    point := primitives.Vector{X: -local.Y, Y: local.X, Z: 5}
+    normal := primitives.Vector{X: -localNormal.Y, Y: localNormal.X}
+
+    checkInstanceHits(t, instance, []instanceTest{
+        {"along the long axis", primitives.Vector{Y: 10, Z: 5}, primitives.Vector{Y: 2, Z: 5}, 8, primitives.Vector{Y: 1}},
+        {"along the short axis", primitives.Vector{X: 10, Z: 5}, primitives.Vector{X: 1, Z: 5}, 9, primitives.Vector{X: 1}},
+        {"oblique", point.Add(normal.Mult(3)), point, 3, normal},
+    })
+}
+
+func TestInstanceTriangle(t *testing.T) {
+    trg := newTestTriangle(primitives.Vector{X: 1}, primitives.Vector{Z: 1}, primitives.Vector{X: 1, Y: 1})
+    instance, err := NewInstance(objectMesh{trg}, primitives.Scaling(primitives.Vector{X: 2, Y: 1, Z: 1}), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    // the local plane x + z = 1 becomes x / 2 + z = 1, scaling the normal with the matrix would give (2, 0, 1)
+    normal := primitives.Vector{X: -1, Z: -2}.Norm()
+    point := primitives.Vector{X: 1, Y: 0.25, Z: 0.5}
+    checkInstanceHits(t, instance, []instanceTest{
+        {"front", point.Sub(normal.Mult(3)), point, 3, normal},
+        {"back", point.Add(normal.Mult(0.5)), point, 0.5, normal},
+    })
+
+    ray := NewRay(point.Sub(normal.Mult(3)), point)
+    hit, _ := castAt(instance, ray)
+    if !primitives.Equal(hit.Coefficient.U, 0.5) || !primitives.Equal(hit.Coefficient.V, 0.25) {
+        t.Errorf("barycentrics = %v, %v, want 0.5, 0.25", hit.Coefficient.U, hit.Coefficient.V)
+    }
+}
+
+func TestInstanceSingular(t *testing.T) {
+    mesh := objectMesh{NewSphere(primitives.Vector{}, 1, nil)}
+    if _, err := NewInstance(mesh, primitives.Scaling(primitives.Vector{X: 1, Y: 0, Z: 1}), nil); err == nil {
+        t.Error("a flattening transform is accepted")
+    }
+}
+
+// TestMovingObject checks that a moving sphere is hit where it is at the time of the ray
+func TestMovingObject(t *testing.T) {
+    sphere := NewSphere(primitives.Vector{}, 1, nil)
+    end := primitives.Translation(primitives.Vector{X: 4}).Mult(primitives.Scaling(primitives.Vector{X: 3, Y: 3, Z: 3}))
+    moving, err := NewMovingObject(sphere, primitives.Identity(), end)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, test := range []struct {
+        time, coef float64
+    }{{0, 9}, {0.5, 8}, {1, 7}} {
+        center := primitives.Vector{X: 4 * test.time}
+        ray := NewRay(center.Add(primitives.Vector{Z: 10}), center)
+        ray.Time = test.time
+        hit, ok := castAt(moving, ray)
+        if !ok || !primitives.Equal(hit.Coefficient.IntersectionCoef, test.coef) {
+            t.Errorf("time %v: coef = %v, want %v", test.time, hit.Coefficient.IntersectionCoef, test.coef)
+            continue
+        }
+        if normal := hit.Object.GetNormal(&hit); !vectorsClose(normal, primitives.Vector{Z: 1}) {
+            t.Errorf("time %v: normal = %v", test.time, normal)
+        }
+        if !moving.GetBoundingBox().Contains(hit.Point) {
+            t.Errorf("time %v: hit %v lies outside the swept bounding box", test.time, hit.Point)
+        }
+    }
+}
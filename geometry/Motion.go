@@ -0,0 +1,155 @@
+package geometry
+
+import (
+    "errors"
+    "math"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+// MOTION_BBOX_STEPS is the number of times the transform is sampled to bound the volume swept by a moving object
+const MOTION_BBOX_STEPS int = 32
+
+// transform places an object in the world, it moves from start at time 0 to end at time 1
+type transform struct {
+    start, end primitives.Matrix4
+    moving     bool
+    // toLocal and normalToWorld of a still object are computed once
+    toLocal, normalToWorld primitives.Matrix4
+}
+
+func newTransform(start, end primitives.Matrix4) (transform, error) {
+    startToLocal, ok := start.Inverse()
+    if !ok {
+        return transform{}, errors.New("transform is not invertible")
+    }
+    if _, ok := end.Inverse(); !ok {
+        return transform{}, errors.New("transform is not invertible")
+    }
+    if start.Determinant3() * end.Determinant3() < 0 {
+        // the interpolated scale would pass through zero
+        return transform{}, errors.New("start and end transforms must have the same handedness")
+    }
+    return transform{start: start, end: end, moving: start != end,
+        toLocal: startToLocal, normalToWorld: startToLocal.Transpose()}, nil
+}
+
+// at returns the world to local transform at time and the transform of normals back to the world
+func (tr *transform) at(time float64) (primitives.Matrix4, primitives.Matrix4) {
+    if !tr.moving {
+        return tr.toLocal, tr.normalToWorld
+    }
+    toLocal, ok := primitives.Interpolate(tr.start, tr.end, primitives.Clamp(0, 1, time)).Inverse()
+    if !ok {
+        return tr.toLocal, tr.normalToWorld
+    }
+    return toLocal, toLocal.Transpose()
+}
+
+// toLocalRay moves the ray into the object space without normalising its direction,
+// so the coefficient of a hit is the same in both spaces
+func (tr *transform) toLocalRay(ray *Ray) (Ray, primitives.Matrix4) {
+    toLocal, normalToWorld := tr.at(ray.Time)
+    return Ray{
+        Begin:     toLocal.TransformPoint(ray.Begin),
+        Direction: toLocal.TransformDirection(ray.Direction),
+        Time:      ray.Time,
+    }, normalToWorld
+}
+
+// getBoundingBox covers local transformed by the whole motion. The rotation is sampled, the corners
+// between two samples stay within the sagitta of the arc they follow, which pads the box.
+func (tr *transform) getBoundingBox(local *BBox) *BBox {
+    if !local.IsBounded() {
+        return local
+    }
+    corners := make([]primitives.Vector, 0, 8)
+    for _, x := range []float64{local.Left.X, local.Right.X} {
+        for _, y := range []float64{local.Left.Y, local.Right.Y} {
+            for _, z := range []float64{local.Left.Z, local.Right.Z} {
+                corners = append(corners, primitives.Vector{X: x, Y: y, Z: z})
+            }
+        }
+    }
+    steps := 1
+    if tr.moving {
+        steps = MOTION_BBOX_STEPS
+    }
+    world := make([]primitives.Vector, 0, len(corners) * (steps + 1))
+    radius := 0.0
+    for step := 0; step <= steps; step++ {
+        toWorld := primitives.Interpolate(tr.start, tr.end, float64(step) / float64(steps))
+        origin := toWorld.TransformPoint(primitives.Vector{})
+        for _, corner := range corners {
+            point := toWorld.TransformPoint(corner)
+            radius = math.Max(radius, point.Sub(origin).Length())
+            world = append(world, point)
+        }
+    }
+    bbox := CreateFromPoints(world)
+    if tr.moving {
+        // the shortest arc turns by at most pi over the whole frame
+        pad := radius * (1 - math.Cos(math.Pi / float64(2 * steps)))
+        padding := primitives.Vector{X: pad, Y: pad, Z: pad}
+        bbox = &BBox{bbox.Left.Sub(padding), bbox.Right.Add(padding)}
+    }
+    return bbox
+}
+
+// MovingObject moves Object, given in its own space, from the Start transform at time 0 to the End one at time 1
+type MovingObject struct {
+    Object IGeometryObject
+
+    transform transform
+    bbox      *BBox
+}
+
+func NewMovingObject(object IGeometryObject, start, end primitives.Matrix4) (*MovingObject, error) {
+    tr, err := newTransform(start, end)
+    if err != nil {
+        return nil, err
+    }
+    return &MovingObject{Object: object, transform: tr, bbox: tr.getBoundingBox(object.GetBoundingBox())}, nil
+}
+
+func (obj *MovingObject) GetNormal(hit *Intersection) primitives.Vector {
+    return hit.Coefficient.Object.GetNormal(hit)
+}
+
+func (obj *MovingObject) GetTexturePoint(hit *Intersection) primitives.Vector {
+    return hit.Coefficient.Object.GetTexturePoint(hit)
+}
+
+func (obj *MovingObject) GetObjectId() int {
+    return obj.Object.GetObjectId()
+}
+
+// GetBoundingBox covers the whole volume swept by the object during the frame
+func (obj *MovingObject) GetBoundingBox() *BBox {
+    return obj.bbox
+}
+
+func (obj *MovingObject) Intersect(ray *Ray) RayCoefIntersection {
+    localRay, normalToWorld := obj.transform.toLocalRay(ray)
+    coef := obj.Object.Intersect(&localRay)
+    if !coef.HasIntersection {
+        return RayCoefIntersection{}
+    }
+    local := Intersection{
+        Coefficient: coef,
+        Point:       localRay.Begin.Add(localRay.Direction.Mult(coef.IntersectionCoef)),
+        Object:      obj.Object,
+        Time:        ray.Time,
+    }
+    if coef.Object != nil {
+        local.Object = coef.Object
+    }
+    result := coef
+    result.Object = &transformedHit{owner: obj, local: local, normalToWorld: normalToWorld,
+        objectId: local.Object.GetObjectId()}
+    return result
+}
+
+func (obj *MovingObject) GetMaterial() *materials.Material {
+    return obj.Object.GetMaterial()
+}
@@ -0,0 +1,45 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/primitives"
+)
+
+// GenerateNormals computes smooth vertex normals for a triangle soup. Corners sharing a
+// position average the area weighted normals of the faces around them, faces meeting at
+// an angle wider than creaseAngle (in degrees) keep a hard edge.
+func GenerateNormals(faces [][3]primitives.Vector, creaseAngle float64) [][3]primitives.Vector {
+    faceNormals := make([]primitives.Vector, len(faces))
+    unitNormals := make([]primitives.Vector, len(faces))
+    corners := make(map[primitives.Vector][]int)
+    for i, face := range faces {
+        // the length of the cross product is twice the face area
+        faceNormals[i] = face[1].Sub(face[0]).Cross(face[2].Sub(face[0]))
+        if length := faceNormals[i].Length(); length > 0 {
+            unitNormals[i] = faceNormals[i].Div(length)
+        }
+        for _, point := range face {
+            corners[point] = append(corners[point], i)
+        }
+    }
+
+    minCosine := math.Cos(creaseAngle * math.Pi / 180)
+    normals := make([][3]primitives.Vector, len(faces))
+    for i, face := range faces {
+        for corner, point := range face {
+            var normal primitives.Vector
+            for _, neighbour := range corners[point] {
+                if neighbour == i || unitNormals[i].Dot(unitNormals[neighbour]) >= minCosine {
+                    normal = normal.Add(faceNormals[neighbour])
+                }
+            }
+            if length := normal.Length(); length > 0 {
+                normal = normal.Div(length)
+            } else {
+                normal = unitNormals[i]
+            }
+            normals[i][corner] = normal
+        }
+    }
+    return normals
+}
@@ -0,0 +1,59 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+// PLANE_PARALLEL_EPS is the smallest cosine between a ray and a plane normal that still intersects
+const PLANE_PARALLEL_EPS float64 = 1e-12
+
+// Plane is infinite, its bounding box is too and it has to be kept out of the KD-tree
+type Plane struct {
+    ObjectId
+    Point, Normal primitives.Vector
+    Material      *materials.Material
+    frame         frame
+}
+
+func NewPlane(point, normal primitives.Vector, material *materials.Material) *Plane {
+    normal = normal.Norm()
+    return &Plane{Point: point, Normal: normal, Material: material, frame: newFrame(point, normal)}
+}
+
+func (p *Plane) GetNormal(hit *Intersection) primitives.Vector {
+    return p.Normal
+}
+
+// GetTexturePoint uses the coordinates inside the plane, the texture repeats every unit
+func (p *Plane) GetTexturePoint(hit *Intersection) primitives.Vector {
+    local := p.frame.toLocal(hit.Point)
+    return primitives.Vector{X: local.X, Y: local.Y}
+}
+
+func (p *Plane) GetBoundingBox() *BBox {
+    infinity := primitives.Vector{X: math.Inf(1), Y: math.Inf(1), Z: math.Inf(1)}
+    return &BBox{infinity.Mult(-1), infinity}
+}
+
+func (p *Plane) Intersect(ray *Ray) RayCoefIntersection {
+    coef, ok := intersectPlane(ray, p.Point, p.Normal)
+    if !ok {
+        return RayCoefIntersection{}
+    }
+    return NewRayCoefIntersection(coef)
+}
+
+func (p *Plane) GetMaterial() *materials.Material {
+    return p.Material
+}
+
+// intersectPlane returns the coefficient of the hit in front of the ray with the plane through point
+func intersectPlane(ray *Ray, point, normal primitives.Vector) (float64, bool) {
+    cosine := ray.Direction.Dot(normal)
+    if math.Abs(cosine) <= PLANE_PARALLEL_EPS * ray.Direction.Length() {
+        return 0, false
+    }
+    return nearestRoot(point.Sub(ray.Begin).Dot(normal) / cosine)
+}
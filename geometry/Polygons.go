@@ -1,83 +1,156 @@
 package geometry
 
 import (
+    "math"
     "ray-tracing/materials"
     "ray-tracing/primitives"
 )
 
 type IGeometryObject interface {
-    GetNormal(primitives.Vector) primitives.Vector
-    GetTexturePoint(pos primitives.Vector) primitives.Vector
+    GetNormal(hit *Intersection) primitives.Vector
+    GetTexturePoint(hit *Intersection) primitives.Vector
     GetBoundingBox() *BBox
     Intersect(ray *Ray) RayCoefIntersection
     GetMaterial() *materials.Material
+    GetObjectId() int
+}
+
+// ObjectId tells which scene object a primitive belongs to, primitives embed it
+type ObjectId int
+
+func (id ObjectId) GetObjectId() int {
+    return int(id)
+}
+
+func (id *ObjectId) SetObjectId(value int) {
+    *id = ObjectId(value)
 }
 
 type Triangle struct {
+    ObjectId
     points [3]primitives.Vector
     textureCoords [3]primitives.Vector
     material *materials.Material
+    // vertexNormals are interpolated over the face when smooth is set
+    vertexNormals [3]primitives.Vector
+    smooth bool
 
-    normal      primitives.Vector
-    surfaceArea float64
-    planeCoefficient float64
+    normal primitives.Vector
+    // degenerate triangles have no area and are never hit
+    degenerate bool
 }
 
-func (trg *Triangle) GetNormal(primitives.Vector) primitives.Vector {
-    return trg.normal
-}
-
-func calculateArea(trg *Triangle) float64 {
-    return trg.points[1].Sub(trg.points[0]).Cross(trg.points[2].Sub(trg.points[0])).Length()
+func (trg *Triangle) GetNormal(hit *Intersection) primitives.Vector {
+    if !trg.smooth {
+        return trg.normal
+    }
+    weights := getBarycentric(hit)
+    normal := trg.vertexNormals[0].Mult(weights[0]).
+        Add(trg.vertexNormals[1].Mult(weights[1])).
+        Add(trg.vertexNormals[2].Mult(weights[2]))
+    if primitives.Equal(normal.Length(), 0) {
+        return trg.normal
+    }
+    return normal.Norm()
 }
 
-func calculateNormal(trg *Triangle) float64 {
-    trg.normal = trg.points[1].Sub(trg.points[0]).Cross(trg.points[2].Sub(trg.points[0])).Norm()
-    return trg.normal.Dot(trg.points[0])
+// getBarycentric returns the weights of the three vertices stored in the hit by Intersect
+func getBarycentric(hit *Intersection) [3]float64 {
+    u, v := hit.Coefficient.U, hit.Coefficient.V
+    return [3]float64{1 - u - v, u, v}
 }
 
 func NewTriangle(points [3]primitives.Vector, textureCoords [3]primitives.Vector, material *materials.Material) *Triangle {
     var triangle = &Triangle{points: points, textureCoords: textureCoords, material: material}
-    triangle.surfaceArea = calculateArea(triangle)
-    triangle.planeCoefficient = calculateNormal(triangle)
+    cross := points[1].Sub(points[0]).Cross(points[2].Sub(points[0]))
+    triangle.degenerate = cross == primitives.Vector{}
+    triangle.normal = cross.Norm()
     return triangle
 }
 
-func (trg *Triangle) GetTexturePoint(pos primitives.Vector) primitives.Vector {
-    tempPos := pos.Sub(trg.points[1])
-    baseU := trg.textureCoords[2].Sub(trg.textureCoords[1])
-    baseV := trg.textureCoords[0].Sub(trg.textureCoords[1])
-    baseX := trg.points[2].Sub(trg.points[1])
-    baseY := trg.points[0].Sub(trg.points[1])
+// NewSmoothTriangle creates a triangle shaded with the interpolated vertex normals
+func NewSmoothTriangle(points [3]primitives.Vector, normals [3]primitives.Vector, textureCoords [3]primitives.Vector,
+    material *materials.Material) *Triangle {
+    triangle := NewTriangle(points, textureCoords, material)
+    triangle.vertexNormals = normals
+    triangle.smooth = true
+    return triangle
+}
 
-    partU := baseU.Mult(tempPos.Dot(baseX)).Div(baseX.SqrLength())
-    partV := baseV.Mult(tempPos.Dot(baseY)).Div(baseY.SqrLength())
-    return partU.Add(partV)
+// GetTexturePoint interpolates the texture coordinates of the vertices at the hit
+func (trg *Triangle) GetTexturePoint(hit *Intersection) primitives.Vector {
+    weights := getBarycentric(hit)
+    return trg.textureCoords[0].Mult(weights[0]).
+        Add(trg.textureCoords[1].Mult(weights[1])).
+        Add(trg.textureCoords[2].Mult(weights[2]))
 }
 
+// Intersect is the watertight test of Woop, Benthin and Wald. The vertices are moved into a
+// space where the ray starts at the origin and goes along +Z, there the hit is decided by the
+// signs of three 2D edge functions. Neighbouring triangles evaluate their shared edge with the
+// same operations, so a ray passing through the edge or a shared vertex hits at least one of them.
 func (trg *Triangle) Intersect(ray *Ray) RayCoefIntersection {
-    coef := (trg.planeCoefficient - ray.Begin.Dot(trg.normal)) / (ray.Direction.Dot(trg.normal))
-    point := ray.Begin.Add(ray.Direction.Mult(coef))
-
-    area := 0.0
-
-    for i := 0; i < 3; i++ {
-        ind1, ind2 := i % 3, (i + 1) % 3
-        area += point.Sub(trg.points[ind1]).Cross(point.Sub(trg.points[ind2])).Length()
+    if trg.degenerate {
+        return RayCoefIntersection{}
+    }
+    direction := components(ray.Direction)
+    kz := 0
+    for axis := 1; axis < 3; axis++ {
+        if math.Abs(direction[axis]) > math.Abs(direction[kz]) {
+            kz = axis
+        }
+    }
+    if direction[kz] == 0 {
+        return RayCoefIntersection{}
+    }
+    kx, ky := (kz + 1) % 3, (kz + 2) % 3
+    if direction[kz] < 0 {
+        // keeps the winding of the triangle in the sheared space
+        kx, ky = ky, kx
+    }
+    shearX, shearY, shearZ := direction[kx] / direction[kz], direction[ky] / direction[kz], 1 / direction[kz]
+
+    var x, y, z [3]float64
+    for i, point := range trg.points {
+        local := components(point.Sub(ray.Begin))
+        x[i] = local[kx] - shearX * local[kz]
+        y[i] = local[ky] - shearY * local[kz]
+        z[i] = shearZ * local[kz]
     }
 
-    if !primitives.Equal(area, trg.surfaceArea) || primitives.Less(area * trg.surfaceArea, 0) {
-        return RayCoefIntersection{
-            HasIntersection:  false,
-            IntersectionCoef: 0,
+    // edge functions of the edges opposite to every vertex
+    var edges [3]float64
+    for i := range edges {
+        j, k := (i + 1) % 3, (i + 2) % 3
+        edges[i] = x[k] * y[j] - y[k] * x[j]
+        if edges[i] == 0 {
+            // rounding may have cancelled a tiny value, its sign decides rays through edges and vertices
+            edges[i] = differenceOfProducts(x[k], y[j], y[k], x[j])
         }
     }
+    if (edges[0] < 0 || edges[1] < 0 || edges[2] < 0) && (edges[0] > 0 || edges[1] > 0 || edges[2] > 0) {
+        return RayCoefIntersection{}
+    }
+    determinant := edges[0] + edges[1] + edges[2]
+    if determinant == 0 {
+        // the ray is parallel to the plane of the triangle
+        return RayCoefIntersection{}
+    }
     return RayCoefIntersection{
         HasIntersection:  true,
-        IntersectionCoef: coef,
+        IntersectionCoef: (edges[0] * z[0] + edges[1] * z[1] + edges[2] * z[2]) / determinant,
+        U:                edges[1] / determinant,
+        V:                edges[2] / determinant,
     }
 }
 
+// differenceOfProducts returns a * b - c * d with Kahan's fused multiply-add algorithm,
+// which is accurate to a few ulps and zero only when the exact result is
+func differenceOfProducts(a, b, c, d float64) float64 {
+    cd := c * d
+    return math.FMA(a, b, -cd) + math.FMA(-c, d, cd)
+}
+
 func (trg *Triangle) GetBoundingBox() *BBox {
     return CreateFromPoints(trg.points[:])
 }
@@ -0,0 +1,163 @@
+package geometry
+
+import (
+    "math"
+    "math/rand"
+    "ray-tracing/primitives"
+    "testing"
+)
+
+func newTestTriangle(a, b, c primitives.Vector) *Triangle {
+    return NewTriangle([3]primitives.Vector{a, b, c}, [3]primitives.Vector{}, nil)
+}
+
+func TestTriangleIntersect(t *testing.T) {
+    trg := newTestTriangle(primitives.Vector{}, primitives.Vector{X: 1}, primitives.Vector{Y: 1})
+    tests := []struct {
+        name       string
+        ray        Ray
+        hit        bool
+        coef, u, v float64
+    }{
+        {"inside", Ray{Begin: primitives.Vector{X: 0.25, Y: 0.5, Z: 2}, Direction: primitives.Vector{Z: -1}}, true, 2, 0.25, 0.5},
+        {"back face", Ray{Begin: primitives.Vector{X: 0.5, Y: 0.25, Z: -1}, Direction: primitives.Vector{Z: 1}}, true, 1, 0.5, 0.25},
+        {"vertex", Ray{Begin: primitives.Vector{X: 1, Z: 1}, Direction: primitives.Vector{Z: -1}}, true, 1, 1, 0},
+        {"outside", Ray{Begin: primitives.Vector{X: 0.75, Y: 0.75, Z: 1}, Direction: primitives.Vector{Z: -1}}, false, 0, 0, 0},
+        {"behind", Ray{Begin: primitives.Vector{X: 0.25, Y: 0.25, Z: 1}, Direction: primitives.Vector{Z: 1}}, true, -1, 0.25, 0.25},
+        {"unnormalised direction", Ray{Begin: primitives.Vector{X: 0.25, Y: 0.25, Z: 4}, Direction: primitives.Vector{Z: -2}}, true, 2, 0.25, 0.25},
+    }
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            result := trg.Intersect(&test.ray)
+            if result.HasIntersection != test.hit {
+                t.Fatalf("HasIntersection = %v, want %v", result.HasIntersection, test.hit)
+            }
+            if !test.hit {
+                return
+            }
+            if !primitives.Equal(result.IntersectionCoef, test.coef) || !primitives.Equal(result.U, test.u) ||
+                !primitives.Equal(result.V, test.v) {
+                t.Errorf("coef, u, v = %v, %v, %v, want %v, %v, %v",
+                    result.IntersectionCoef, result.U, result.V, test.coef, test.u, test.v)
+            }
+        })
+    }
+}
+
+// testPlaneHeight tilts the test meshes, they stay flat so every ray crossing them inside has to hit
+func testPlaneHeight(x, y float64) float64 {
+    return 0.3 * x - 0.2 * y + 0.5
+}
+
+// newTestFan builds a fan of triangles around a centre on the test plane
+func newTestFan(x, y float64, count int) ([]*Triangle, []primitives.Vector) {
+    center := primitives.Vector{X: x, Y: y, Z: testPlaneHeight(x, y)}
+    rim := make([]primitives.Vector, count)
+    for i := range rim {
+        angle := 2 * math.Pi * float64(i) / float64(count)
+        rx, ry := x + 1.7 * math.Cos(angle), y + 1.3 * math.Sin(angle)
+        rim[i] = primitives.Vector{X: rx, Y: ry, Z: testPlaneHeight(rx, ry)}
+    }
+    fan := make([]*Triangle, count)
+    for i := range fan {
+        fan[i] = newTestTriangle(center, rim[i], rim[(i + 1) % count])
+    }
+    return fan, rim
+}
+
+func hitsAny(triangles []*Triangle, ray *Ray) bool {
+    for _, trg := range triangles {
+        if trg.Intersect(ray).HasIntersection {
+            return true
+        }
+    }
+    return false
+}
+
+// TestTriangleWatertight shoots rays from many origins through the shared edges and vertices
+// of a fan, every one of them has to hit at least one of its triangles
+func TestTriangleWatertight(t *testing.T) {
+    rng := rand.New(rand.NewSource(7))
+    fan, rim := newTestFan(0.1, -0.3, 7)
+    center := fan[0].points[0]
+    normal := fan[0].normal
+    for i := 0; i < 2000; i++ {
+        origin := primitives.Vector{X: rng.NormFloat64(), Y: rng.NormFloat64(), Z: rng.NormFloat64()}.Norm().
+            Mult(1 + rng.Float64() * 20)
+        if rng.Intn(2) == 0 {
+            // grazing origins close to the plane of the fan
+            origin = origin.Sub(normal.Mult(origin.Sub(center).Dot(normal))).Add(normal.Mult((rng.Float64() - 0.5) * 0.1))
+        }
+        targets := []primitives.Vector{center}
+        for _, vertex := range rim {
+            targets = append(targets, center.Add(vertex.Sub(center).Mult(rng.Float64() * 0.95)))
+        }
+        for _, target := range targets {
+            ray := &Ray{Begin: origin, Direction: target.Sub(origin)}
+            if !hitsAny(fan, ray) {
+                t.Fatalf("ray from %v towards %v passes through the fan", origin, target)
+            }
+        }
+    }
+}
+
+// TestTriangleSharedVertex aims at the inner vertices of a grid, each shared by six
+// triangles, with directions that are not normalised so the shear has to handle them as they are
+func TestTriangleSharedVertex(t *testing.T) {
+    const side = 6
+    vertex := func(i, j int) primitives.Vector {
+        x, y := 0.37 * float64(i) - 1, 0.29 * float64(j) + 0.5
+        return primitives.Vector{X: x, Y: y, Z: testPlaneHeight(x, y)}
+    }
+    var grid []*Triangle
+    for i := 0; i < side; i++ {
+        for j := 0; j < side; j++ {
+            grid = append(grid, newTestTriangle(vertex(i, j), vertex(i + 1, j), vertex(i + 1, j + 1)),
+                newTestTriangle(vertex(i, j), vertex(i + 1, j + 1), vertex(i, j + 1)))
+        }
+    }
+    rng := rand.New(rand.NewSource(3))
+    for n := 0; n < 200; n++ {
+        x, y := rng.Float64() * 40 - 20, rng.Float64() * 40 - 20
+        origin := primitives.Vector{X: x, Y: y, Z: testPlaneHeight(x, y) + rng.Float64() * 20 + 0.01}
+        if n % 2 == 1 {
+            origin.Z -= 2 * (origin.Z - testPlaneHeight(x, y))
+        }
+        for i := 1; i < side; i++ {
+            for j := 1; j < side; j++ {
+                ray := &Ray{Begin: origin, Direction: vertex(i, j).Sub(origin).Mult(rng.Float64() * 5 + 0.1)}
+                if !hitsAny(grid, ray) {
+                    t.Fatalf("ray from %v misses the shared vertex %v", origin, vertex(i, j))
+                }
+            }
+        }
+    }
+}
+
+func TestTriangleParallelRay(t *testing.T) {
+    trg := newTestTriangle(primitives.Vector{}, primitives.Vector{X: 1}, primitives.Vector{Y: 1})
+    rays := []Ray{
+        {Begin: primitives.Vector{X: -1, Y: 0.25}, Direction: primitives.Vector{X: 1}},
+        {Begin: primitives.Vector{X: -1, Y: 0.25, Z: 0.5}, Direction: primitives.Vector{X: 1}},
+        {Begin: primitives.Vector{X: 0.25, Y: -1}, Direction: primitives.Vector{X: 0.6, Y: 0.8}},
+    }
+    for _, ray := range rays {
+        if trg.Intersect(&ray).HasIntersection {
+            t.Errorf("ray %v parallel to the triangle hits it", ray)
+        }
+    }
+}
+
+func TestTriangleDegenerate(t *testing.T) {
+    triangles := map[string]*Triangle{
+        "collinear": newTestTriangle(primitives.Vector{}, primitives.Vector{X: 1}, primitives.Vector{X: 2}),
+        "repeated vertex": newTestTriangle(primitives.Vector{}, primitives.Vector{X: 1}, primitives.Vector{X: 1}),
+        "point": newTestTriangle(primitives.Vector{}, primitives.Vector{}, primitives.Vector{}),
+    }
+    ray := Ray{Begin: primitives.Vector{X: 0.5, Z: 1}, Direction: primitives.Vector{Z: -1}}
+    for name, trg := range triangles {
+        if result := trg.Intersect(&ray); result.HasIntersection {
+            t.Errorf("%s triangle is hit at %v", name, result.IntersectionCoef)
+        }
+    }
+}
@@ -0,0 +1,134 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/primitives"
+)
+
+// POLYNOMIAL_BISECTION_STEPS halves the bracket of a root down to the float64 precision
+const POLYNOMIAL_BISECTION_STEPS int = 100
+// POLYNOMIAL_TOUCH_EPS is the value relative to the size of the terms below which an extremum is taken for a root
+const POLYNOMIAL_TOUCH_EPS float64 = 1e-12
+
+// solveQuadratic returns the real roots of a*t^2 + b*t + c in ascending order
+func solveQuadratic(a, b, c float64) []float64 {
+    if a == 0 {
+        if b == 0 {
+            return nil
+        }
+        return []float64{-c / b}
+    }
+    discriminant := b * b - 4 * a * c
+    if discriminant < 0 {
+        return nil
+    }
+    // q avoids the cancellation of -b + sqrt(discriminant) when b is large
+    q := -0.5 * (b + math.Copysign(math.Sqrt(discriminant), b))
+    if q == 0 {
+        return []float64{0}
+    }
+    first, second := q / a, c / q
+    if first > second {
+        first, second = second, first
+    }
+    return []float64{first, second}
+}
+
+// nearestRoot returns the smallest root in front of the ray origin
+func nearestRoot(roots ...float64) (float64, bool) {
+    nearest, found := math.Inf(1), false
+    for _, root := range roots {
+        if root > primitives.EPS && root < nearest {
+            nearest, found = root, true
+        }
+    }
+    return nearest, found
+}
+
+func evaluatePolynomial(coefs []float64, t float64) float64 {
+    result := 0.0
+    for _, coef := range coefs {
+        result = result * t + coef
+    }
+    return result
+}
+
+// evaluateMagnitude sums the absolute values of the terms at t, the rounding error of
+// evaluatePolynomial is proportional to it
+func evaluateMagnitude(coefs []float64, t float64) float64 {
+    result := 0.0
+    for _, coef := range coefs {
+        result = result * math.Abs(t) + math.Abs(coef)
+    }
+    return result
+}
+
+// solvePolynomial finds the real roots inside [low, high] of the polynomial whose coefficients
+// go from the highest degree down. The roots of the derivative split the range into monotonic
+// pieces, each holding at most one root that is then found by bisection.
+func solvePolynomial(coefs []float64, low, high float64) []float64 {
+    for len(coefs) > 0 && coefs[0] == 0 {
+        coefs = coefs[1:]
+    }
+    var roots []float64
+    if len(coefs) <= 3 {
+        if len(coefs) == 3 {
+            roots = solveQuadratic(coefs[0], coefs[1], coefs[2])
+        } else if len(coefs) == 2 {
+            roots = solveQuadratic(0, coefs[0], coefs[1])
+        }
+        inside := roots[:0]
+        for _, root := range roots {
+            if root >= low && root <= high {
+                inside = append(inside, root)
+            }
+        }
+        return inside
+    }
+
+    degree := len(coefs) - 1
+    derivative := make([]float64, degree)
+    for i := range derivative {
+        derivative[i] = coefs[i] * float64(degree - i)
+    }
+    bounds := append([]float64{low}, solvePolynomial(derivative, low, high)...)
+    bounds = append(bounds, high)
+
+    values := make([]float64, len(bounds))
+    for i, bound := range bounds {
+        values[i] = evaluatePolynomial(coefs, bound)
+        if i > 0 && i + 1 < len(bounds) && math.Abs(values[i]) <= POLYNOMIAL_TOUCH_EPS * evaluateMagnitude(coefs, bound) {
+            // an extremum touching zero is a double root, rounding would otherwise hide it
+            values[i] = 0
+        }
+    }
+
+    for i := 0; i + 1 < len(bounds); i++ {
+        left, right := bounds[i], bounds[i + 1]
+        leftValue, rightValue := values[i], values[i + 1]
+        if i == 0 && leftValue == 0 {
+            roots = append(roots, left)
+        }
+        if rightValue == 0 {
+            roots = append(roots, right)
+            continue
+        }
+        if leftValue == 0 || (leftValue < 0) == (rightValue < 0) {
+            continue
+        }
+        for step := 0; step < POLYNOMIAL_BISECTION_STEPS && left < right; step++ {
+            middle := (left + right) / 2
+            if middle == left || middle == right {
+                break
+            }
+            middleValue := evaluatePolynomial(coefs, middle)
+            if (middleValue < 0) == (leftValue < 0) {
+                left, leftValue = middle, middleValue
+            } else {
+                right = middle
+            }
+        }
+        roots = append(roots, (left + right) / 2)
+    }
+    return roots
+}
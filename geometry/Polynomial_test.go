@@ -0,0 +1,102 @@
+package geometry
+
+import (
+    "math"
+    "testing"
+)
+
+func rootsEqual(roots, want []float64, tolerance float64) bool {
+    if len(roots) != len(want) {
+        return false
+    }
+    for i := range roots {
+        if math.Abs(roots[i] - want[i]) > tolerance * math.Max(1, math.Abs(want[i])) {
+            return false
+        }
+    }
+    return true
+}
+
+func TestSolveQuadratic(t *testing.T) {
+    tests := []struct {
+        name    string
+        a, b, c float64
+        want    []float64
+    }{
+        {"two roots", 1, -3, 2, []float64{1, 2}},
+        {"negative leading coefficient", -2, 2, 4, []float64{-1, 2}},
+        {"double root", 1, -2, 1, []float64{1, 1}},
+        {"no real roots", 1, 0, 1, nil},
+        {"root at zero", 1, -1, 0, []float64{0, 1}},
+        {"double root at zero", 1, 0, 0, []float64{0}},
+        {"linear", 0, 2, -4, []float64{2}},
+        {"constant", 0, 0, 1, nil},
+        {"cancellation", 1, 1e8, 1, []float64{-1e8, -1e-8}},
+    }
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            if roots := solveQuadratic(test.a, test.b, test.c); !rootsEqual(roots, test.want, 1e-12) {
+                t.Errorf("solveQuadratic(%v, %v, %v) = %v, want %v", test.a, test.b, test.c, roots, test.want)
+            }
+        })
+    }
+}
+
+// expand multiplies the monic polynomials with the given roots
+func expand(roots ...float64) []float64 {
+    coefs := []float64{1}
+    for _, root := range roots {
+        next := make([]float64, len(coefs) + 1)
+        for i, coef := range coefs {
+            next[i] += coef
+            next[i + 1] -= coef * root
+        }
+        coefs = next
+    }
+    return coefs
+}
+
+func TestSolvePolynomial(t *testing.T) {
+    tests := []struct {
+        name      string
+        coefs     []float64
+        low, high float64
+        want      []float64
+        tolerance float64
+    }{
+        {"four roots", expand(1, 2, 3, 4), 0, 5, []float64{1, 2, 3, 4}, 1e-12},
+        {"range clips roots", expand(1, 2, 3, 4), 1.5, 3.5, []float64{2, 3}, 1e-12},
+        {"double root", expand(2, 2, 5, 7), 0, 10, []float64{2, 5, 7}, 1e-12},
+        {"small double root", expand(0.5, 0.5, 3, 8), 0, 10, []float64{0.5, 3, 8}, 1e-12},
+        // rounding leaves the extremum of these slightly above zero
+        {"double root missed by rounding", expand(2.6, 2.6, 7.4, 13.8), 0, 20, []float64{2.6, 7.4, 13.8}, 1e-6},
+        {"another double root missed by rounding", expand(0.7, 0.7, 7.4, 13.8), 0, 20, []float64{0.7, 7.4, 13.8}, 1e-6},
+        // a double root is only known to about the square root of the precision
+        {"two double roots", expand(0.3, 0.3, 1.7, 1.7), 0, 3, []float64{0.3, 1.7}, 1e-6},
+        {"no real roots", []float64{1, 0, 2, 0, 1}, -10, 10, nil, 0},
+        {"leading zeros", append([]float64{0, 0}, expand(1, 2)...), 0, 3, []float64{1, 2}, 1e-12},
+        {"linear", []float64{0, 0, 0, 2, -3}, 0, 3, []float64{1.5}, 1e-12},
+        {"root at the low bound", expand(0, 1, 2, 3), 0, 4, []float64{0, 1, 2, 3}, 1e-12},
+        {"cubic", expand(-1, 0.5, 2), -2, 3, []float64{-1, 0.5, 2}, 1e-12},
+    }
+    for _, test := range tests {
+        t.Run(test.name, func(t *testing.T) {
+            roots := dedupeRoots(solvePolynomial(test.coefs, test.low, test.high), test.tolerance)
+            if !rootsEqual(roots, test.want, test.tolerance) {
+                t.Errorf("solvePolynomial(%v, %v, %v) = %v, want %v", test.coefs, test.low, test.high, roots, test.want)
+            }
+        })
+    }
+}
+
+// dedupeRoots merges the roots a double root may be reported as
+func dedupeRoots(roots []float64, tolerance float64) []float64 {
+    var result []float64
+    for _, root := range roots {
+        if len(result) > 0 && math.Abs(root - result[len(result) - 1]) <= tolerance * math.Max(1, math.Abs(root)) {
+            continue
+        }
+        result = append(result, root)
+    }
+    return result
+}
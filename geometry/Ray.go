@@ -7,11 +7,17 @@ import (
 type Ray struct {
     Begin     primitives.Vector
     Direction primitives.Vector
+    // Time inside the shutter interval, moving objects are intersected at their position at this time
+    Time float64
 }
 
 type RayCoefIntersection struct {
     HasIntersection  bool
     IntersectionCoef float64
+    // U and V are the barycentric coordinates of the hit on a triangle, the weights of its second and third vertex
+    U, V float64
+    // Object replaces the intersected object in the hit when set, instances use it to report what they hit inside
+    Object IGeometryObject
 }
 
 type Intersection struct {
@@ -19,12 +25,20 @@ type Intersection struct {
     Point       primitives.Vector
     Object      IGeometryObject
     Color       primitives.Color
+    Time        float64
 }
 
 func NewRay(begin primitives.Vector, end primitives.Vector) *Ray {
     return &Ray{Begin: begin, Direction: end.Sub(begin).Norm()}
 }
 
+// Spawn starts a secondary ray from begin towards end at the time of ray
+func (ray *Ray) Spawn(begin primitives.Vector, end primitives.Vector) *Ray {
+    newRay := NewRay(begin, end)
+    newRay.Time = ray.Time
+    return newRay
+}
+
 func NewRayCoefIntersection(value float64) RayCoefIntersection {
     return RayCoefIntersection{HasIntersection: true, IntersectionCoef: value}
 }
@@ -42,5 +56,5 @@ func (ray *Ray) GetReflectRay(point primitives.Vector, normal primitives.Vector)
     dir := ray.Direction.Mult(-1.0)
     normDir := dir.Sub(normal.Mult(dir.Dot(normal)))
     newDir := dir.Sub(normDir.Mult(2.0))
-    return &Ray{point, point.Add(newDir)}
+    return ray.Spawn(point, point.Add(newDir))
 }
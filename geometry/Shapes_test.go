@@ -0,0 +1,141 @@
+package geometry
+
+import (
+    "math"
+    "math/rand"
+    "ray-tracing/primitives"
+    "testing"
+)
+
+const SHAPE_TEST_EPS float64 = 1e-7
+
+func vectorsClose(a, b primitives.Vector) bool {
+    return a.Sub(b).Length() < SHAPE_TEST_EPS
+}
+
+// castAt intersects ray with obj and returns the hit the way the KD-tree reports it
+func castAt(obj IGeometryObject, ray *Ray) (Intersection, bool) {
+    coef := obj.Intersect(ray)
+    if !coef.HasIntersection || coef.IntersectionCoef <= 0 {
+        return Intersection{}, false
+    }
+    hit := Intersection{Coefficient: coef, Point: ray.Begin.Add(ray.Direction.Mult(coef.IntersectionCoef)), Object: obj}
+    if coef.Object != nil {
+        hit.Object = coef.Object
+    }
+    return hit, true
+}
+
+func testShapes() map[string]IGeometryObject {
+    diagonal := 1 / math.Sqrt2
+    return map[string]IGeometryObject{
+        "sphere": NewSphere(primitives.Vector{X: 1, Y: 2, Z: 3}, 2, nil),
+        "plane": NewPlane(primitives.Vector{Y: -1}, primitives.Vector{Y: 1}, nil),
+        "disk": NewDisk(primitives.Vector{}, primitives.Vector{Z: 1}, 1, nil),
+        "box": NewBox(primitives.Vector{}, primitives.Vector{X: 2, Y: 4, Z: 6}, nil),
+        "oriented box": NewOrientedBox(primitives.Vector{}, primitives.Vector{X: 2, Y: 2, Z: 2},
+            [3]primitives.Vector{{X: diagonal, Y: diagonal}, {X: -diagonal, Y: diagonal}, {Z: 1}}, nil),
+        "cylinder": NewCylinder(primitives.Vector{}, primitives.Vector{Y: 1}, 1, 2, nil),
+        "cone": NewCone(primitives.Vector{}, primitives.Vector{Y: 1}, 1, 2, nil),
+        "torus": NewTorus(primitives.Vector{}, primitives.Vector{Y: 1}, 2, 0.5, nil),
+        "tilted torus": NewTorus(primitives.Vector{X: 1}, primitives.Vector{X: 1, Y: 1}, 1.5, 0.25, nil),
+        "tilted cone": NewCone(primitives.Vector{Z: -1}, primitives.Vector{X: 1, Z: 2}, 0.5, 3, nil),
+        "tilted cylinder": NewCylinder(primitives.Vector{Y: 1}, primitives.Vector{Y: 1, Z: -1}, 0.75, 1.5, nil),
+    }
+}
+
+func TestShapeHits(t *testing.T) {
+    shapes := testShapes()
+    diagonal := 1 / math.Sqrt2
+    tests := []struct {
+        shape      string
+        begin, end primitives.Vector
+        hit        bool
+        coef       float64
+        normal     primitives.Vector
+    }{
+        {"sphere", primitives.Vector{X: 1, Y: 2, Z: 10}, primitives.Vector{X: 1, Y: 2}, true, 5, primitives.Vector{Z: 1}},
+        {"sphere", primitives.Vector{X: 1, Y: 2, Z: 3}, primitives.Vector{X: 2, Y: 2, Z: 3}, true, 2, primitives.Vector{X: 1}},
+        {"sphere", primitives.Vector{X: 4, Y: 2, Z: 10}, primitives.Vector{X: 4, Y: 2}, false, 0, primitives.Vector{}},
+        {"plane", primitives.Vector{X: 3, Y: 4, Z: 5}, primitives.Vector{X: 3, Z: 5}, true, 5, primitives.Vector{Y: 1}},
+        {"plane", primitives.Vector{X: 3, Y: 4, Z: 5}, primitives.Vector{X: 4, Y: 4, Z: 5}, false, 0, primitives.Vector{}},
+        {"disk", primitives.Vector{X: 0.5, Z: 3}, primitives.Vector{X: 0.5}, true, 3, primitives.Vector{Z: 1}},
+        {"disk", primitives.Vector{X: 1.5, Z: 3}, primitives.Vector{X: 1.5}, false, 0, primitives.Vector{}},
+        {"box", primitives.Vector{X: 5}, primitives.Vector{}, true, 4, primitives.Vector{X: 1}},
+        {"box", primitives.Vector{Z: -10}, primitives.Vector{}, true, 7, primitives.Vector{Z: -1}},
+        {"box", primitives.Vector{X: 5, Y: 2.5}, primitives.Vector{Y: 2.5}, false, 0, primitives.Vector{}},
+        {"oriented box", primitives.Vector{X: 5, Y: 5}, primitives.Vector{}, true, 5 * math.Sqrt2 - 1,
+            primitives.Vector{X: diagonal, Y: diagonal}},
+        {"cylinder", primitives.Vector{X: 5, Y: 1}, primitives.Vector{Y: 1}, true, 4, primitives.Vector{X: 1}},
+        {"cylinder", primitives.Vector{X: 0.5, Y: 10}, primitives.Vector{X: 0.5}, true, 8, primitives.Vector{Y: 1}},
+        {"cylinder", primitives.Vector{X: 0.5, Y: -10}, primitives.Vector{X: 0.5}, true, 10, primitives.Vector{Y: -1}},
+        {"cylinder", primitives.Vector{X: 5, Y: 3}, primitives.Vector{Y: 3}, false, 0, primitives.Vector{}},
+        {"cone", primitives.Vector{X: 5, Y: 1}, primitives.Vector{Y: 1}, true, 4.5,
+            primitives.Vector{X: 2 / math.Sqrt(5), Y: 1 / math.Sqrt(5)}},
+        {"cone", primitives.Vector{X: 0.2, Y: -5}, primitives.Vector{X: 0.2}, true, 5, primitives.Vector{Y: -1}},
+        {"cone", primitives.Vector{X: 5, Y: 2.5}, primitives.Vector{Y: 2.5}, false, 0, primitives.Vector{}},
+        // the other nappe of the double cone lies above the apex
+        {"cone", primitives.Vector{X: 5, Y: 3}, primitives.Vector{Y: 3}, false, 0, primitives.Vector{}},
+        {"torus", primitives.Vector{X: 2, Y: 5}, primitives.Vector{X: 2}, true, 4.5, primitives.Vector{Y: 1}},
+        {"torus", primitives.Vector{X: 10}, primitives.Vector{}, true, 7.5, primitives.Vector{X: 1}},
+        {"torus", primitives.Vector{X: 1}, primitives.Vector{}, true, 2.5, primitives.Vector{X: 1}},
+        {"torus", primitives.Vector{Y: 5}, primitives.Vector{}, false, 0, primitives.Vector{}},
+        // a tangent ray touches the tube at a double root of the quartic
+        {"torus", primitives.Vector{X: 10, Y: 0.5}, primitives.Vector{Y: 0.5}, true, 8, primitives.Vector{Y: 1}},
+    }
+    for _, test := range tests {
+        ray := NewRay(test.begin, test.end)
+        hit, ok := castAt(shapes[test.shape], ray)
+        if ok != test.hit {
+            t.Errorf("%s from %v: hit = %v, want %v", test.shape, test.begin, ok, test.hit)
+            continue
+        }
+        if !ok {
+            continue
+        }
+        // a tangent hit is only known to about the square root of the precision
+        if math.Abs(hit.Coefficient.IntersectionCoef - test.coef) > 1e-6 {
+            t.Errorf("%s from %v: coef = %v, want %v", test.shape, test.begin, hit.Coefficient.IntersectionCoef, test.coef)
+        }
+        if normal := hit.Object.GetNormal(&hit); normal.Sub(test.normal).Length() > 1e-3 {
+            t.Errorf("%s from %v: normal = %v, want %v", test.shape, test.begin, normal, test.normal)
+        }
+    }
+}
+
+// TestShapeBoundingBoxes shoots random rays at every bounded shape and checks that
+// the hits lie inside its bounding box and that the normals have unit length
+func TestShapeBoundingBoxes(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    randomVector := func(size float64) primitives.Vector {
+        return primitives.Vector{X: rng.Float64() * 2 - 1, Y: rng.Float64() * 2 - 1, Z: rng.Float64() * 2 - 1}.Mult(size)
+    }
+    for name, shape := range testShapes() {
+        bbox := shape.GetBoundingBox()
+        if !bbox.IsBounded() {
+            continue
+        }
+        center := bbox.Left.Add(bbox.Right).Mult(0.5)
+        slack := primitives.Vector{X: SHAPE_TEST_EPS, Y: SHAPE_TEST_EPS, Z: SHAPE_TEST_EPS}
+        padded := BBox{bbox.Left.Sub(slack), bbox.Right.Add(slack)}
+        hits := 0
+        for i := 0; i < 2000; i++ {
+            begin := center.Add(randomVector(1).Norm().Mult(20))
+            ray := NewRay(begin, center.Add(randomVector(3)))
+            hit, ok := castAt(shape, ray)
+            if !ok {
+                continue
+            }
+            hits++
+            if !padded.Contains(hit.Point) {
+                t.Fatalf("%s: hit %v lies outside its bounding box %v", name, hit.Point, *bbox)
+            }
+            if normal := hit.Object.GetNormal(&hit); math.Abs(normal.Length() - 1) > SHAPE_TEST_EPS {
+                t.Fatalf("%s: normal %v at %v is not a unit vector", name, normal, hit.Point)
+            }
+        }
+        if hits == 0 {
+            t.Errorf("%s: no random ray hit the shape", name)
+        }
+    }
+}
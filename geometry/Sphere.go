@@ -2,43 +2,53 @@ package geometry
 
 import (
     "math"
+    "ray-tracing/materials"
     "ray-tracing/primitives"
 )
 
 type Sphere struct {
-    Center primitives.Vector
-    Radius float64
+    ObjectId
+    Center   primitives.Vector
+    Radius   float64
+    Material *materials.Material
 }
 
-func (s Sphere) GetNormal(pos primitives.Vector) primitives.Vector {
-    norm := pos.Sub(s.Center)
-    if primitives.Greater(norm.Length(), s.Radius) {
-        panic("normal length more than Radius")
-    }
-    return norm.Norm()
+func NewSphere(center primitives.Vector, radius float64, material *materials.Material) *Sphere {
+    return &Sphere{Center: center, Radius: radius, Material: material}
 }
 
-func (s Sphere) GetTexturePoint(pos primitives.Vector) primitives.Vector {
-    panic("implement me")
+// GetNormal points away from the centre, hits are never exactly on the surface so
+// the distance to the centre is not checked against the radius
+func (s *Sphere) GetNormal(hit *Intersection) primitives.Vector {
+    return hit.Point.Sub(s.Center).Norm()
 }
 
-func (s Sphere) GetBoundingBox() *BBox {
-    radiusVector := primitives.Vector{s.Radius, s.Radius, s.Radius}
-    return &BBox{s.Center.Sub(radiusVector), s.Center.Add(radiusVector)}
+// GetTexturePoint maps longitude around the Y axis to u and latitude from the south pole to v
+func (s *Sphere) GetTexturePoint(hit *Intersection) primitives.Vector {
+    direction := hit.Point.Sub(s.Center).Norm()
+    return primitives.Vector{
+        X: 0.5 + math.Atan2(direction.Z, direction.X) / (2 * math.Pi),
+        Y: 0.5 + math.Asin(primitives.Clamp(-1, 1, direction.Y)) / math.Pi,
+    }
 }
 
-func (s Sphere) Intersect(ray *Ray) RayCoefIntersection {
-    distance := ray.Distance(s.Center)
+func (s *Sphere) GetBoundingBox() *BBox {
+    radiusVector := primitives.Vector{X: s.Radius, Y: s.Radius, Z: s.Radius}
+    return &BBox{s.Center.Sub(radiusVector), s.Center.Add(radiusVector)}
+}
 
-    if primitives.Greater(distance, s.Radius) {
-        return RayCoefIntersection{}
+// Intersect returns the nearest of the two roots of |begin + t * direction - center| = radius
+// in front of the ray, so rays starting inside the sphere hit its far side
+func (s *Sphere) Intersect(ray *Ray) RayCoefIntersection {
+    offset := ray.Begin.Sub(s.Center)
+    roots := solveQuadratic(ray.Direction.Dot(ray.Direction), 2 * offset.Dot(ray.Direction),
+        offset.Dot(offset) - s.Radius * s.Radius)
+    if coef, ok := nearestRoot(roots...); ok {
+        return NewRayCoefIntersection(coef)
     }
+    return RayCoefIntersection{}
+}
 
-    scalarDistance := ray.GetLineCoef(s.Center)
-    halfSphereDistance := math.Sqrt(math.Abs(s.Radius* s.Radius - distance * distance))
-    rayD := math.Min(scalarDistance - halfSphereDistance, scalarDistance + halfSphereDistance)
-    if primitives.Less(rayD, 0) {
-        rayD = math.Max(scalarDistance - halfSphereDistance, scalarDistance + halfSphereDistance)
-    }
-    return RayCoefIntersection{IntersectionCoef: rayD, HasIntersection: true}
+func (s *Sphere) GetMaterial() *materials.Material {
+    return s.Material
 }
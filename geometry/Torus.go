@@ -0,0 +1,92 @@
+package geometry
+
+import (
+    "math"
+    "ray-tracing/materials"
+    "ray-tracing/primitives"
+)
+
+// Torus is the surface at MinorRadius from the circle of MajorRadius around Axis through Center
+type Torus struct {
+    ObjectId
+    Center, Axis             primitives.Vector
+    MajorRadius, MinorRadius float64
+    Material                 *materials.Material
+    frame                    frame
+}
+
+func NewTorus(center, axis primitives.Vector, majorRadius, minorRadius float64, material *materials.Material) *Torus {
+    axis = axis.Norm()
+    return &Torus{Center: center, Axis: axis, MajorRadius: majorRadius, MinorRadius: minorRadius,
+        Material: material, frame: newFrame(center, axis)}
+}
+
+// getRingPoint returns the point of the central circle nearest to a local point
+func (t *Torus) getRingPoint(local primitives.Vector) primitives.Vector {
+    distance := math.Hypot(local.X, local.Y)
+    if distance == 0 {
+        return primitives.Vector{X: t.MajorRadius}
+    }
+    return primitives.Vector{X: local.X, Y: local.Y}.Mult(t.MajorRadius / distance)
+}
+
+func (t *Torus) GetNormal(hit *Intersection) primitives.Vector {
+    local := t.frame.toLocal(hit.Point)
+    return t.frame.directionToWorld(local.Sub(t.getRingPoint(local))).Norm()
+}
+
+// GetTexturePoint maps the angle around the axis to u and the angle around the tube to v
+func (t *Torus) GetTexturePoint(hit *Intersection) primitives.Vector {
+    local := t.frame.toLocal(hit.Point)
+    return primitives.Vector{
+        X: getAngleTextureCoord(local),
+        Y: 0.5 + math.Atan2(local.Z, math.Hypot(local.X, local.Y) - t.MajorRadius) / (2 * math.Pi),
+    }
+}
+
+func (t *Torus) GetBoundingBox() *BBox {
+    bbox := discBoundingBox(t.Center, t.Axis, t.MajorRadius + t.MinorRadius)
+    tube := primitives.Vector{
+        X: t.MinorRadius * math.Abs(t.Axis.X), Y: t.MinorRadius * math.Abs(t.Axis.Y), Z: t.MinorRadius * math.Abs(t.Axis.Z),
+    }
+    return &BBox{bbox.Left.Sub(tube), bbox.Right.Add(tube)}
+}
+
+// Intersect solves the quartic (|p|^2 + R^2 - r^2)^2 = 4 R^2 (x^2 + y^2) along the ray. The ray is
+// first clipped by the bounding sphere, which keeps the coefficients small and the search range short.
+func (t *Torus) Intersect(ray *Ray) RayCoefIntersection {
+    begin, direction := t.frame.rayToLocal(ray)
+    outer := t.MajorRadius + t.MinorRadius
+    sphere := solveQuadratic(direction.Dot(direction), 2 * begin.Dot(direction), begin.Dot(begin) - outer * outer)
+    if len(sphere) < 2 || sphere[1] <= primitives.EPS {
+        return RayCoefIntersection{}
+    }
+    start := math.Max(0, sphere[0])
+    begin = begin.Add(direction.Mult(start))
+
+    major2 := t.MajorRadius * t.MajorRadius
+    m := direction.Dot(direction)
+    n := begin.Dot(direction)
+    k := begin.Dot(begin) + major2 - t.MinorRadius * t.MinorRadius
+    a := direction.X * direction.X + direction.Y * direction.Y
+    b := begin.X * direction.X + begin.Y * direction.Y
+    c := begin.X * begin.X + begin.Y * begin.Y
+    roots := solvePolynomial([]float64{
+        m * m,
+        4 * m * n,
+        4 * n * n + 2 * m * k - 4 * major2 * a,
+        4 * n * k - 8 * major2 * b,
+        k * k - 4 * major2 * c,
+    }, 0, sphere[1] - start)
+    for i := range roots {
+        roots[i] += start
+    }
+    if coef, ok := nearestRoot(roots...); ok {
+        return NewRayCoefIntersection(coef)
+    }
+    return RayCoefIntersection{}
+}
+
+func (t *Torus) GetMaterial() *materials.Material {
+    return t.Material
+}
@@ -26,6 +26,13 @@ type KDTree struct {
     TotalBuildingTime time.Duration
 }
 
+// TraversalStats counts the work done while casting rays through the tree,
+// it is not synchronised so every goroutine should keep its own copy
+type TraversalStats struct {
+    NodeVisits int64
+    ObjectTests int64
+}
+
 type BBoxSplit struct {
     value float64
     axis int
@@ -178,13 +185,19 @@ func recBuild(input kdTreeNodeInput, sync chan int) {
     return
 }
 
-func findIntersection(node *KDTreeNode, ray *geometry.Ray) geometry.Intersection {
+func findIntersection(node *KDTreeNode, ray *geometry.Ray, stats *TraversalStats) geometry.Intersection {
+    if stats != nil {
+        stats.NodeVisits++
+    }
     if node.nodeSize == 0 || !node.bbox.Intersect(ray)[0].HasIntersection {
         return geometry.Intersection{}
     }
     if len(node.objects) != 0 {
         var intersection geometry.Intersection
         currentCoef := math.MaxFloat64
+        if stats != nil {
+            stats.ObjectTests += int64(len(node.objects))
+        }
 
         for _, obj := range node.objects {
             objIntersection := obj.Intersect(ray)
@@ -193,16 +206,20 @@ func findIntersection(node *KDTreeNode, ray *geometry.Ray) geometry.Intersection
                 currentCoef = objIntersection.IntersectionCoef
 
                 intersection = geometry.Intersection{
-                    Coefficient: geometry.RayCoefIntersection{IntersectionCoef: currentCoef, HasIntersection: true},
+                    Coefficient: objIntersection,
                     Point:       ray.Begin.Add(ray.Direction.Mult(currentCoef)),
                     Object:      obj,
+                    Time:        ray.Time,
+                }
+                if objIntersection.Object != nil {
+                    intersection.Object = objIntersection.Object
                 }
             }
         }
         return intersection
     }
-    left := findIntersection(node.left, ray)
-    right := findIntersection(node.right, ray)
+    left := findIntersection(node.left, ray, stats)
+    right := findIntersection(node.right, ray, stats)
     if left.Coefficient.HasIntersection {
         if right.Coefficient.HasIntersection && primitives.Greater(left.Coefficient.IntersectionCoef, right.Coefficient.IntersectionCoef) {
             return right
@@ -216,6 +233,11 @@ func (tree *KDTree) BuildTree(objects []geometry.IGeometryObject) {
     buildingBegin := time.Now()
     sync := make(chan int)
     tree.root = new(KDTreeNode)
+    if len(objects) == 0 {
+        // scenes made of unbounded objects only leave the tree empty
+        tree.TotalBuildingTime = time.Since(buildingBegin)
+        return
+    }
     go recBuild(kdTreeNodeInput{tree.root, objects, getBoundingBox(objects)}, sync)
     sum := 1
     for obj := range sync {
@@ -229,10 +251,19 @@ func (tree *KDTree) BuildTree(objects []geometry.IGeometryObject) {
     tree.TotalBuildingTime = buildingEnd.Sub(buildingBegin)
 }
 
-func (tree *KDTree) CastRay(ray *geometry.Ray) geometry.Intersection {
-    if !tree.root.bbox.Intersect(ray)[0].HasIntersection {
+// CastRay returns the closest intersection along ray, stats may be nil
+func (tree *KDTree) CastRay(ray *geometry.Ray, stats *TraversalStats) geometry.Intersection {
+    if tree.root.nodeSize == 0 || !tree.root.bbox.Intersect(ray)[0].HasIntersection {
         return geometry.Intersection{}
     }
-    return findIntersection(tree.root, ray)
+    return findIntersection(tree.root, ray, stats)
 }
 
+// Intersect makes the tree usable as the mesh of instances, the traversal is not counted
+func (tree *KDTree) Intersect(ray *geometry.Ray) geometry.Intersection {
+    return tree.CastRay(ray, nil)
+}
+
+func (tree *KDTree) GetBoundingBox() *geometry.BBox {
+    return tree.root.bbox
+}
@@ -1,13 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
 	"os"
+	"os/signal"
+	"ray-tracing/film"
 	"ray-tracing/scene"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/jessevdk/go-flags"
@@ -24,6 +26,97 @@ func MaxParallelism() int {
 
 type options struct {
 	Filename string `long:"config" required:"true"`
+	Samples  int    `long:"samples" description:"Antialiasing samples per pixel, overrides the scene file"`
+	Pattern  string `long:"pattern" description:"Antialiasing sample pattern: jittered or stratified"`
+
+	AdaptiveThreshold float64 `long:"adaptive-threshold" description:"Enable the edge antialiasing pass with the given L1 colour difference"`
+	AdaptiveSamples   int     `long:"adaptive-samples" description:"Samples per pixel in the edge antialiasing pass"`
+
+	Integrator    string `long:"integrator" description:"Light transport algorithm: whitted or path"`
+	ShadowSamples int    `long:"shadow-samples" description:"Samples taken on every area light when estimating shadows"`
+
+	Exposure    *float64 `long:"exposure" description:"Exposure correction in stops"`
+	ToneMapping string   `long:"tonemap" description:"Tone mapping operator: clamp, reinhard or aces"`
+	Linear      bool     `long:"linear" description:"Write linear values instead of sRGB encoded ones"`
+
+	TileSize  int    `long:"tile-size" description:"Side of the square render tiles in pixels"`
+	TileOrder string `long:"tile-order" description:"Order the tiles are rendered in: scanline, spiral or hilbert"`
+	Workers   int    `long:"workers" description:"Number of render workers, the available parallelism by default"`
+
+	Timeout time.Duration `long:"timeout" description:"Abort the render after the given duration, e.g. 30s"`
+
+	Progressive      bool          `long:"progressive" description:"Refine the image in passes, stop it with Ctrl+C or --timeout"`
+	Passes           int           `long:"passes" description:"Number of progressive passes, unlimited by default"`
+	SamplesPerPass   int           `long:"samples-per-pass" description:"Samples per pixel in every progressive pass"`
+	SnapshotInterval time.Duration `long:"snapshot-interval" description:"Minimal time between writing progressive snapshots"`
+
+	Output         string `long:"output" default:"results/res.png" description:"Output image, the extension selects the format: png, hdr or exr"`
+	EXRCompression string `long:"exr-compression" default:"zip" description:"OpenEXR compression: none or zip"`
+
+	Region string `long:"region" description:"Render only the rectangle x,y,width,height of the viewport"`
+	Crop   bool   `long:"crop" description:"Write only the region instead of a full-size image"`
+
+	AOVs []string `long:"aov" description:"Also write the given buffer: depth, normal, albedo, material or object; repeatable"`
+
+	Stats      string `long:"stats" description:"Print render statistics after the render: text or json"`
+	NoProgress bool   `long:"no-progress" description:"Do not show the progress bar"`
+}
+
+const PROGRESS_BAR_WIDTH = 40
+
+// showProgress redraws a progress bar with an ETA on stderr until done is closed
+func showProgress(curScene *scene.Scene, renderBegin time.Time, done chan struct{}) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			drawProgress(curScene, renderBegin)
+			fmt.Fprintln(os.Stderr)
+			return
+		case <-ticker.C:
+			drawProgress(curScene, renderBegin)
+		}
+	}
+}
+
+func drawProgress(curScene *scene.Scene, renderBegin time.Time) {
+	finished, total := curScene.Progress()
+	if total == 0 {
+		return
+	}
+	fraction := float64(finished) / float64(total)
+	filled := int(fraction * PROGRESS_BAR_WIDTH)
+	eta := "?"
+	if finished > 0 {
+		elapsed := time.Since(renderBegin)
+		eta = time.Duration(float64(elapsed) * (1 - fraction) / fraction).Round(time.Second).String()
+	}
+	fmt.Fprintf(os.Stderr, "\r[%s%s] %5.1f%% ETA %-8s", strings.Repeat("#", filled),
+		strings.Repeat(".", PROGRESS_BAR_WIDTH-filled), fraction*100, eta)
+}
+
+func validateStatisticsFormat(format string) error {
+	switch format {
+	case "", "json", "text":
+		return nil
+	default:
+		return fmt.Errorf("unknown statistics format %q", format)
+	}
+}
+
+func printStatistics(stats scene.Statistics, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		fmt.Print(stats)
+	}
+	return nil
 }
 
 func main() {
@@ -32,35 +125,131 @@ func main() {
 	if _, err := parser.Parse(); err != nil {
 		panic(err)
 	}
+	if err := validateStatisticsFormat(opts.Stats); err != nil {
+		panic(err)
+	}
 	fmt.Println(MaxParallelism())
 	curScene, err := scene.OpenScene(opts.Filename)
 	if err != nil {
 		panic(err)
 	}
+	if opts.Samples > 0 {
+		curScene.Antialiasing.Samples = opts.Samples
+	}
+	if opts.Pattern != "" {
+		curScene.Antialiasing.Pattern = scene.SamplePattern(opts.Pattern)
+		if err := curScene.Antialiasing.Validate(); err != nil {
+			panic(err)
+		}
+	}
+	if opts.AdaptiveThreshold > 0 {
+		curScene.Antialiasing.Adaptive.Enabled = true
+		curScene.Antialiasing.Adaptive.Threshold = opts.AdaptiveThreshold
+	}
+	if opts.AdaptiveSamples > 0 {
+		curScene.Antialiasing.Adaptive.Samples = opts.AdaptiveSamples
+	}
+	if opts.Integrator != "" {
+		curScene.Integrator = scene.Integrator(opts.Integrator)
+		if err := curScene.Integrator.Validate(); err != nil {
+			panic(err)
+		}
+	}
+	if opts.ShadowSamples > 0 {
+		curScene.ShadowSamples = opts.ShadowSamples
+	}
+	if opts.Exposure != nil {
+		curScene.Film.Exposure = *opts.Exposure
+	}
+	if opts.ToneMapping != "" {
+		curScene.Film.ToneMapping = film.ToneMapping(opts.ToneMapping)
+		if err := curScene.Film.Validate(); err != nil {
+			panic(err)
+		}
+	}
+	if opts.Linear {
+		curScene.Film.Linear = true
+	}
+	if opts.TileSize > 0 {
+		curScene.Scheduler.TileSize = opts.TileSize
+	}
+	if opts.TileOrder != "" {
+		curScene.Scheduler.Order = scene.TileOrder(opts.TileOrder)
+		if err := curScene.Scheduler.Validate(); err != nil {
+			panic(err)
+		}
+	}
+	if opts.Workers > 0 {
+		curScene.Scheduler.Workers = opts.Workers
+	} else if curScene.Scheduler.Workers <= 0 {
+		curScene.Scheduler.Workers = MaxParallelism()
+	}
+	if opts.Progressive {
+		curScene.Progressive.Enabled = true
+	}
+	if opts.Passes > 0 {
+		curScene.Progressive.Passes = opts.Passes
+	}
+	if opts.SamplesPerPass > 0 {
+		curScene.Progressive.SamplesPerPass = opts.SamplesPerPass
+	}
+	if opts.SnapshotInterval > 0 {
+		curScene.Progressive.SnapshotSeconds = opts.SnapshotInterval.Seconds()
+	}
+	if opts.Region != "" {
+		region := scene.Region{}
+		if _, err := fmt.Sscanf(opts.Region, "%d,%d,%d,%d", &region.X, &region.Y, &region.Width, &region.Height); err != nil {
+			panic(fmt.Errorf("region must be given as x,y,width,height: %v", err))
+		}
+		curScene.Region = &region
+	}
+	if opts.Crop {
+		if curScene.Region == nil {
+			panic("--crop needs a region")
+		}
+		curScene.Region.Crop = true
+	}
+	for _, aov := range opts.AOVs {
+		curScene.AOVs = append(curScene.AOVs, scene.AOV(aov))
+	}
+	if err := scene.ValidateAOVs(curScene.AOVs); err != nil {
+		panic(err)
+	}
+	if err := film.ValidateEXRCompression(film.EXRCompression(opts.EXRCompression)); err != nil {
+		panic(err)
+	}
+	curScene.OnSnapshot = func(passes int) error {
+		return writeOutput(curScene, opts.Output, film.EXRCompression(opts.EXRCompression))
+	}
 	fmt.Printf("Initialisation: %.4fs\n", curScene.KDTree.TotalBuildingTime.Seconds())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
 	renderBegin := time.Now()
-	curScene.Render()
-	fmt.Println("Waiting")
-	curScene.Wg.Wait()
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	go func() {
+		if !opts.NoProgress {
+			showProgress(curScene, renderBegin, progressDone)
+		}
+		close(progressStopped)
+	}()
+	err = curScene.Render(ctx)
+	close(progressDone)
+	<-progressStopped
+	if err != nil {
+		panic(err)
+	}
 	renderEnd := time.Now()
 	fmt.Printf("Render time: %.4fs\n", renderEnd.Sub(renderBegin).Seconds())
-	result := image.NewRGBA(image.Rect(0, 0, curScene.Viewport.Width, curScene.Viewport.Height))
-	pixels := curScene.Pixels
-	for x := 0; x < len(pixels); x++ {
-		for y := 0; y < len(pixels[x]); y++ {
-			result.SetRGBA(x, y, color.RGBA{
-				R: uint8(255 * pixels[x][y].R),
-				G: uint8(255 * pixels[x][y].G),
-				B: uint8(255 * pixels[x][y].B),
-				A: 255,
-			})
-		}
+	if err := printStatistics(curScene.GetStatistics(), opts.Stats); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
-	_ = os.Mkdir("results", os.ModePerm)
-	writer, _ := os.Create("results/res.png")
-	err = png.Encode(writer, result)
-	if err != nil {
+	if err := writeOutput(curScene, opts.Output, film.EXRCompression(opts.EXRCompression)); err != nil {
 		panic(err)
 	}
-	_ = writer.Close()
 }
@@ -0,0 +1,83 @@
+package materials
+
+import (
+    "bufio"
+    "os"
+    "ray-tracing/primitives"
+    "strconv"
+    "strings"
+
+    "github.com/udhos/gwob"
+)
+
+// NewMaterialFromMTL maps a parsed MTL entry onto a material following the
+// illumination models of the MTL specification. The opacity comes from
+// ReadOpacity because gwob does not keep track of missing d and Tr statements.
+// Colours are converted from space into linear values.
+func NewMaterialFromMTL(mtl *gwob.Material, opacity float64, materialId int, space primitives.ColorSpace) *Material {
+    specular := primitives.Color{R: float64(mtl.Ks[0]), G: float64(mtl.Ks[1]), B: float64(mtl.Ks[2])}.Decode(space)
+
+    var reflect, refract float64
+    switch mtl.Illum {
+    case 3, 5, 8:
+        // reflection without transparency, the mirror strength comes from Ks
+        reflect = (specular.R + specular.G + specular.B) / 3
+    case 4, 6, 7, 9:
+        refract = float64(mtl.Ni)
+    }
+    if !primitives.Equal(opacity, 1) && primitives.LessEqual(refract, 0) {
+        // transparent materials always need an index of refraction
+        refract = float64(mtl.Ni)
+        if primitives.LessEqual(refract, 0) {
+            refract = 1
+        }
+    }
+
+    material := NewMaterial(
+        primitives.Color{R: float64(mtl.Kd[0]), G: float64(mtl.Kd[1]), B: float64(mtl.Kd[2])}.Decode(space),
+        reflect, refract, opacity, materialId, &mtl.Name,
+    )
+    if mtl.Illum >= 2 {
+        material.Specular = specular
+        material.Shininess = float64(mtl.Ns)
+    }
+    return material
+}
+
+// ReadOpacity returns the opacity of every material in an MTL file, taken
+// from d or from 1 - Tr, materials without either statement are opaque
+func ReadOpacity(filename string) (map[string]float64, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    opacity := make(map[string]float64)
+    dissolveFound := make(map[string]bool)
+    current := ""
+    scanner := bufio.NewScanner(file)
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 2 {
+            continue
+        }
+        switch fields[0] {
+        case "newmtl":
+            current = strings.Join(fields[1:], " ")
+            opacity[current] = 1
+        case "d", "Tr":
+            value, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+            if err != nil {
+                return nil, err
+            }
+            if fields[0] == "d" {
+                opacity[current] = value
+                dissolveFound[current] = true
+            } else if !dissolveFound[current] {
+                opacity[current] = 1 - value
+            }
+        }
+    }
+    return opacity, scanner.Err()
+}
@@ -2,6 +2,7 @@ package materials
 
 import (
     "ray-tracing/primitives"
+    "ray-tracing/textures"
 )
 
 type MaterialType int8
@@ -18,6 +19,13 @@ type Material struct {
     Reflect, Refract, Alpha float64
     MaterialType            MaterialType
 
+    // Specular and Shininess drive Blinn-Phong highlights
+    Specular  primitives.Color
+    Shininess float64
+
+    // DiffuseMap multiplies Color at every texture point when present
+    DiffuseMap *textures.Texture
+
     MaterialId   int
     MaterialName *string
 }
@@ -39,4 +47,17 @@ func NewMaterial(
     return &Material{
         Color: color, Reflect: reflect, Refract: refract, Alpha: alpha, MaterialType: materialType,
         MaterialId: materialId, MaterialName: materialName}
-}
\ No newline at end of file
+}
+
+// HasTexture tells whether GetColor depends on the texture point
+func (material *Material) HasTexture() bool {
+    return material.DiffuseMap != nil
+}
+
+// GetColor returns the diffuse colour at texturePoint, only its X and Y are used as u and v
+func (material *Material) GetColor(texturePoint primitives.Vector) primitives.Color {
+    if !material.HasTexture() {
+        return material.Color
+    }
+    return material.Color.MultColor(material.DiffuseMap.Sample(texturePoint.X, texturePoint.Y))
+}
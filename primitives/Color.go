@@ -1,11 +1,45 @@
 package primitives
 
-import "math"
+import (
+    "fmt"
+    "math"
+)
 
 type Color struct {
     R, G, B float64
 }
 
+type ColorSpace string
+
+const (
+    // SRGBColorSpace values are encoded with the sRGB transfer curve, it is the default
+    SRGBColorSpace   ColorSpace = "srgb"
+    LinearColorSpace ColorSpace = "linear"
+)
+
+func (space ColorSpace) Validate() error {
+    switch space {
+    case "", SRGBColorSpace, LinearColorSpace:
+        return nil
+    default:
+        return fmt.Errorf("unknown colour space %q", space)
+    }
+}
+
+func SRGBToLinear(value float64) float64 {
+    if value <= 0.04045 {
+        return value / 12.92
+    }
+    return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+func LinearToSRGB(value float64) float64 {
+    if value <= 0.0031308 {
+        return value * 12.92
+    }
+    return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
+
 func (c Color) Mult(f float64) Color {
     return Color{c.R * f, c.G * f, c.B * f}
 }
@@ -14,10 +48,30 @@ func (c Color) Add(o Color) Color {
     return Color{c.R + o.R, c.G + o.G, c.B + o.B}
 }
 
+func (c Color) MultColor(o Color) Color {
+    return Color{c.R * o.R, c.G * o.G, c.B * o.B}
+}
+
 func (c Color) L1Norm(o Color) float64 {
     return math.Abs(c.R - o.R) + math.Abs(c.G - o.G) + math.Abs(c.B - o.B)
 }
 
+// Decode converts a colour given in space into linear values
+func (c Color) Decode(space ColorSpace) Color {
+    if space == LinearColorSpace {
+        return c
+    }
+    return Color{SRGBToLinear(c.R), SRGBToLinear(c.G), SRGBToLinear(c.B)}
+}
+
+// Encode converts a linear colour into space
+func (c Color) Encode(space ColorSpace) Color {
+    if space == LinearColorSpace {
+        return c
+    }
+    return Color{LinearToSRGB(c.R), LinearToSRGB(c.G), LinearToSRGB(c.B)}
+}
+
 func (c Color) Normalize() Color {
     return Color{math.Min(math.Abs(c.R), 1.0), math.Min(math.Abs(c.G), 1.0), math.Min(math.Abs(c.B), 1.0)}
 }
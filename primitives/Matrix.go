@@ -0,0 +1,233 @@
+package primitives
+
+import "math"
+
+// Matrix4 is a row-major transform applied to column vectors, Mult(b) applies b first
+type Matrix4 [4][4]float64
+
+func Identity() Matrix4 {
+    return Matrix4{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}
+}
+
+func Translation(offset Vector) Matrix4 {
+    m := Identity()
+    m[0][3], m[1][3], m[2][3] = offset.X, offset.Y, offset.Z
+    return m
+}
+
+func Scaling(factors Vector) Matrix4 {
+    m := Identity()
+    m[0][0], m[1][1], m[2][2] = factors.X, factors.Y, factors.Z
+    return m
+}
+
+// RotationX rotates by angle radians counterclockwise when looking from +X at the origin
+func RotationX(angle float64) Matrix4 {
+    sin, cos := math.Sincos(angle)
+    return Matrix4{{1, 0, 0, 0}, {0, cos, -sin, 0}, {0, sin, cos, 0}, {0, 0, 0, 1}}
+}
+
+func RotationY(angle float64) Matrix4 {
+    sin, cos := math.Sincos(angle)
+    return Matrix4{{cos, 0, sin, 0}, {0, 1, 0, 0}, {-sin, 0, cos, 0}, {0, 0, 0, 1}}
+}
+
+func RotationZ(angle float64) Matrix4 {
+    sin, cos := math.Sincos(angle)
+    return Matrix4{{cos, -sin, 0, 0}, {sin, cos, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}
+}
+
+// Rotation rotates by angle radians around axis, counterclockwise when axis points to the viewer
+func Rotation(axis Vector, angle float64) Matrix4 {
+    a := axis.Norm()
+    sin, cos := math.Sincos(angle)
+    t := 1 - cos
+    return Matrix4{
+        {t * a.X * a.X + cos, t * a.X * a.Y - sin * a.Z, t * a.X * a.Z + sin * a.Y, 0},
+        {t * a.X * a.Y + sin * a.Z, t * a.Y * a.Y + cos, t * a.Y * a.Z - sin * a.X, 0},
+        {t * a.X * a.Z - sin * a.Y, t * a.Y * a.Z + sin * a.X, t * a.Z * a.Z + cos, 0},
+        {0, 0, 0, 1},
+    }
+}
+
+func (m Matrix4) Mult(q Matrix4) Matrix4 {
+    var result Matrix4
+    for row := 0; row < 4; row++ {
+        for column := 0; column < 4; column++ {
+            for k := 0; k < 4; k++ {
+                result[row][column] += m[row][k] * q[k][column]
+            }
+        }
+    }
+    return result
+}
+
+func (m Matrix4) Transpose() Matrix4 {
+    var result Matrix4
+    for row := 0; row < 4; row++ {
+        for column := 0; column < 4; column++ {
+            result[row][column] = m[column][row]
+        }
+    }
+    return result
+}
+
+// Inverse uses Gauss-Jordan elimination with partial pivoting, ok is false for singular matrices
+func (m Matrix4) Inverse() (inverse Matrix4, ok bool) {
+    inverse = Identity()
+    for column := 0; column < 4; column++ {
+        pivot := column
+        for row := column + 1; row < 4; row++ {
+            if math.Abs(m[row][column]) > math.Abs(m[pivot][column]) {
+                pivot = row
+            }
+        }
+        if math.Abs(m[pivot][column]) < EPS {
+            return Matrix4{}, false
+        }
+        m[column], m[pivot] = m[pivot], m[column]
+        inverse[column], inverse[pivot] = inverse[pivot], inverse[column]
+
+        scale := 1 / m[column][column]
+        for k := 0; k < 4; k++ {
+            m[column][k] *= scale
+            inverse[column][k] *= scale
+        }
+        for row := 0; row < 4; row++ {
+            if row == column {
+                continue
+            }
+            factor := m[row][column]
+            for k := 0; k < 4; k++ {
+                m[row][k] -= factor * m[column][k]
+                inverse[row][k] -= factor * inverse[column][k]
+            }
+        }
+    }
+    return inverse, true
+}
+
+// TransformPoint applies the whole matrix to a point, projective matrices divide by w
+func (m Matrix4) TransformPoint(v Vector) Vector {
+    result := m.TransformDirection(v).Add(Vector{m[0][3], m[1][3], m[2][3]})
+    w := m[3][0] * v.X + m[3][1] * v.Y + m[3][2] * v.Z + m[3][3]
+    if w != 1 && w != 0 {
+        return result.Div(w)
+    }
+    return result
+}
+
+// TransformDirection ignores the translation. Normals are transformed by the
+// transpose of the inverse of the matrix transforming the points.
+func (m Matrix4) TransformDirection(v Vector) Vector {
+    return Vector{
+        m[0][0] * v.X + m[0][1] * v.Y + m[0][2] * v.Z,
+        m[1][0] * v.X + m[1][1] * v.Y + m[1][2] * v.Z,
+        m[2][0] * v.X + m[2][1] * v.Y + m[2][2] * v.Z,
+    }
+}
+
+// Determinant3 is the determinant of the linear part of the transform
+func (m Matrix4) Determinant3() float64 {
+    return m[0][0] * (m[1][1] * m[2][2] - m[1][2] * m[2][1]) -
+        m[0][1] * (m[1][0] * m[2][2] - m[1][2] * m[2][0]) +
+        m[0][2] * (m[1][0] * m[2][1] - m[1][1] * m[2][0])
+}
+
+// Interpolate blends affine transforms at t in [0, 1]. Translation and scale are interpolated
+// linearly and rotation along the shortest arc, so a rotating object keeps its shape.
+// Shear is not preserved, such matrices are treated as their nearest rotation and scale.
+func Interpolate(a, b Matrix4, t float64) Matrix4 {
+    if a == b {
+        return a
+    }
+    translationA, rotationA, scaleA := a.decompose()
+    translationB, rotationB, scaleB := b.decompose()
+    translation := translationA.Mult(1 - t).Add(translationB.Mult(t))
+    scale := scaleA.Mult(1 - t).Add(scaleB.Mult(t))
+    rotation := rotationA.slerp(rotationB, t).matrix()
+    return Translation(translation).Mult(rotation).Mult(Scaling(scale))
+}
+
+// decompose splits an affine transform into translation, rotation and scale along the
+// columns, a mirroring transform gets a negative X scale
+func (m Matrix4) decompose() (Vector, quaternion, Vector) {
+    translation := Vector{m[0][3], m[1][3], m[2][3]}
+    columns := [3]Vector{}
+    scale := Vector{}
+    for i, factor := range []*float64{&scale.X, &scale.Y, &scale.Z} {
+        columns[i] = Vector{m[0][i], m[1][i], m[2][i]}
+        *factor = columns[i].Length()
+    }
+    if m.Determinant3() < 0 {
+        scale.X = -scale.X
+    }
+    rotation := Identity()
+    for i, factor := range []float64{scale.X, scale.Y, scale.Z} {
+        if factor == 0 {
+            continue
+        }
+        axis := columns[i].Div(factor)
+        rotation[0][i], rotation[1][i], rotation[2][i] = axis.X, axis.Y, axis.Z
+    }
+    return translation, quaternionFromMatrix(rotation), scale
+}
+
+// quaternion is a unit rotation quaternion w + xi + yj + zk
+type quaternion struct {
+    w, x, y, z float64
+}
+
+func quaternionFromMatrix(m Matrix4) quaternion {
+    trace := m[0][0] + m[1][1] + m[2][2]
+    var q quaternion
+    switch {
+    case trace > 0:
+        s := 2 * math.Sqrt(trace + 1)
+        q = quaternion{s / 4, (m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s}
+    case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+        s := 2 * math.Sqrt(1 + m[0][0] - m[1][1] - m[2][2])
+        q = quaternion{(m[2][1] - m[1][2]) / s, s / 4, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s}
+    case m[1][1] > m[2][2]:
+        s := 2 * math.Sqrt(1 + m[1][1] - m[0][0] - m[2][2])
+        q = quaternion{(m[0][2] - m[2][0]) / s, (m[0][1] + m[1][0]) / s, s / 4, (m[1][2] + m[2][1]) / s}
+    default:
+        s := 2 * math.Sqrt(1 + m[2][2] - m[0][0] - m[1][1])
+        q = quaternion{(m[1][0] - m[0][1]) / s, (m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, s / 4}
+    }
+    return q.norm()
+}
+
+func (q quaternion) dot(p quaternion) float64 {
+    return q.w * p.w + q.x * p.x + q.y * p.y + q.z * p.z
+}
+
+func (q quaternion) norm() quaternion {
+    length := math.Sqrt(q.dot(q))
+    return quaternion{q.w / length, q.x / length, q.y / length, q.z / length}
+}
+
+// slerp walks the shorter of the two arcs between q and p
+func (q quaternion) slerp(p quaternion, t float64) quaternion {
+    cos := q.dot(p)
+    if cos < 0 {
+        p, cos = quaternion{-p.w, -p.x, -p.y, -p.z}, -cos
+    }
+    a, b := 1 - t, t
+    if cos < 1 - EPS {
+        angle := math.Acos(cos)
+        sin := math.Sin(angle)
+        a, b = math.Sin((1 - t) * angle) / sin, math.Sin(t * angle) / sin
+    }
+    return quaternion{a * q.w + b * p.w, a * q.x + b * p.x, a * q.y + b * p.y, a * q.z + b * p.z}.norm()
+}
+
+func (q quaternion) matrix() Matrix4 {
+    w, x, y, z := q.w, q.x, q.y, q.z
+    return Matrix4{
+        {1 - 2 * (y * y + z * z), 2 * (x * y - w * z), 2 * (x * z + w * y), 0},
+        {2 * (x * y + w * z), 1 - 2 * (x * x + z * z), 2 * (y * z - w * x), 0},
+        {2 * (x * z - w * y), 2 * (y * z + w * x), 1 - 2 * (x * x + y * y), 0},
+        {0, 0, 0, 1},
+    }
+}
@@ -0,0 +1,96 @@
+package primitives
+
+import (
+    "math"
+    "testing"
+)
+
+func matricesClose(a, b Matrix4, tolerance float64) bool {
+    for row := 0; row < 4; row++ {
+        for column := 0; column < 4; column++ {
+            if math.Abs(a[row][column] - b[row][column]) > tolerance {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+func TestMatrixInverse(t *testing.T) {
+    tests := map[string]Matrix4{
+        "identity":    Identity(),
+        "translation": Translation(Vector{X: 1, Y: -2, Z: 3}),
+        "scale":       Scaling(Vector{X: 2, Y: 0.5, Z: -4}),
+        "rotation":    Rotation(Vector{X: 1, Y: 2, Z: 3}, 0.7),
+        "composed": Translation(Vector{X: 5, Y: 0, Z: -1}).
+            Mult(RotationZ(1.1)).Mult(RotationX(-0.4)).Mult(Scaling(Vector{X: 3, Y: 1, Z: 0.25})),
+        "needs pivoting": {{0, 1, 0, 0}, {0, 0, 1, 0}, {1, 0, 0, 0}, {0, 0, 0, 1}},
+        "general":        {{2, -1, 0, 3}, {1, 3, -2, 0}, {0, 4, 1, -1}, {0.5, 0, 2, 1}},
+    }
+    for name, m := range tests {
+        inverse, ok := m.Inverse()
+        if !ok {
+            t.Errorf("%s: matrix is reported singular", name)
+            continue
+        }
+        if product := m.Mult(inverse); !matricesClose(product, Identity(), 1e-12) {
+            t.Errorf("%s: M * M^-1 = %v", name, product)
+        }
+        if product := inverse.Mult(m); !matricesClose(product, Identity(), 1e-12) {
+            t.Errorf("%s: M^-1 * M = %v", name, product)
+        }
+    }
+}
+
+func TestMatrixInverseSingular(t *testing.T) {
+    tests := map[string]Matrix4{
+        "zero":          {},
+        "flattening":    Scaling(Vector{X: 1, Y: 0, Z: 1}),
+        "repeated rows": {{1, 2, 3, 0}, {2, 4, 6, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}},
+        "dependent columns": Translation(Vector{X: 1}).Mult(Matrix4{{1, 1, 0, 0}, {0, 0, 1, 0}, {1, 1, 1, 0}, {0, 0, 0, 1}}),
+    }
+    for name, m := range tests {
+        if _, ok := m.Inverse(); ok {
+            t.Errorf("%s: singular matrix is inverted", name)
+        }
+    }
+}
+
+func TestMatrixTransform(t *testing.T) {
+    m := Translation(Vector{X: 1, Y: 2, Z: 3}).Mult(RotationZ(math.Pi / 2)).Mult(Scaling(Vector{X: 2, Y: 1, Z: 1}))
+    if point := m.TransformPoint(Vector{X: 1}); point.Sub(Vector{X: 1, Y: 4, Z: 3}).Length() > 1e-12 {
+        t.Errorf("TransformPoint = %v", point)
+    }
+    if direction := m.TransformDirection(Vector{X: 1}); direction.Sub(Vector{Y: 2}).Length() > 1e-12 {
+        t.Errorf("TransformDirection = %v", direction)
+    }
+}
+
+func TestInterpolate(t *testing.T) {
+    start := Translation(Vector{X: -1}).Mult(Scaling(Vector{X: 1, Y: 1, Z: 1}))
+    end := Translation(Vector{X: 3, Y: 2}).Mult(RotationZ(math.Pi / 2)).Mult(Scaling(Vector{X: 3, Y: 1, Z: 1}))
+    tests := []struct {
+        time float64
+        want Matrix4
+    }{
+        {0, start},
+        {1, end},
+        // the rotation keeps the shape halfway instead of shrinking like a blend of the matrices
+        {0.5, Translation(Vector{X: 1, Y: 1}).Mult(RotationZ(math.Pi / 4)).Mult(Scaling(Vector{X: 2, Y: 1, Z: 1}))},
+    }
+    for _, test := range tests {
+        if m := Interpolate(start, end, test.time); !matricesClose(m, test.want, 1e-12) {
+            t.Errorf("Interpolate at %v = %v, want %v", test.time, m, test.want)
+        }
+    }
+
+    mirrored := Scaling(Vector{X: -1, Y: 1, Z: 1})
+    moved := Translation(Vector{X: 2}).Mult(mirrored)
+    if m := Interpolate(mirrored, moved, 0.5); !matricesClose(m, Translation(Vector{X: 1}).Mult(mirrored), 1e-12) {
+        t.Errorf("Interpolate of a mirroring transform = %v", m)
+    }
+    turned := RotationY(3)
+    if m := Interpolate(Identity(), turned, 0.5); !matricesClose(m, RotationY(1.5), 1e-12) {
+        t.Errorf("Interpolate of a rotation = %v", m)
+    }
+}
@@ -49,6 +49,16 @@ func (v Vector) Norm() Vector {
     return Vector{v.X / length, v.Y / length, v.Z / length}
 }
 
+// Basis returns two unit vectors forming an orthonormal basis together with the unit vector v
+func (v Vector) Basis() (Vector, Vector) {
+    helper := Vector{1, 0, 0}
+    if math.Abs(v.X) > 0.9 {
+        helper = Vector{0, 1, 0}
+    }
+    u := helper.Cross(v).Norm()
+    return u, v.Cross(u)
+}
+
 func (v Vector) LessEqual(q Vector) bool {
     return LessEqual(v.X, q.X) && LessEqual(v.Y, q.Y) && LessEqual(v.Z, q.Z)
 }
@@ -0,0 +1,242 @@
+package scene
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"ray-tracing/film"
+	"ray-tracing/geometry"
+	"ray-tracing/primitives"
+)
+
+// AOV is an arbitrary output vector, an extra buffer about the first hit of every pixel
+type AOV string
+
+const (
+	// DepthAOV is the distance from the camera to the first hit
+	DepthAOV AOV = "depth"
+	// NormalAOV is the world space normal facing the camera
+	NormalAOV AOV = "normal"
+	// AlbedoAOV is the surface colour without lighting
+	AlbedoAOV   AOV = "albedo"
+	MaterialAOV AOV = "material"
+	ObjectAOV   AOV = "object"
+)
+
+// NO_OBJECT_ID marks the background in the id buffers
+const NO_OBJECT_ID int = -1
+
+func ValidateAOVs(aovs []AOV) error {
+	for _, aov := range aovs {
+		switch aov {
+		case DepthAOV, NormalAOV, AlbedoAOV, MaterialAOV, ObjectAOV:
+		default:
+			return fmt.Errorf("unknown AOV %q", aov)
+		}
+	}
+	return nil
+}
+
+// AOVBuffers are stored as columns like Scene.Pixels. Depth, Normal and Albedo are
+// averaged over the pixel samples, the ids come from the first sample hitting something
+// and are NO_OBJECT_ID where the pixel sees only the background.
+type AOVBuffers struct {
+	Depth      [][]float64
+	Normal     [][]primitives.Vector
+	Albedo     [][]primitives.Color
+	MaterialId [][]int
+	ObjectId   [][]int
+}
+
+// aovPixel accumulates the first hits of the samples of a single pixel
+type aovPixel struct {
+	recorded             bool
+	samples, hits        int
+	depth                float64
+	normal               primitives.Vector
+	albedo               primitives.Color
+	materialId, objectId int
+}
+
+func newAOVBuffers(width, height int) AOVBuffers {
+	buffers := AOVBuffers{
+		Depth:      make([][]float64, width),
+		Normal:     make([][]primitives.Vector, width),
+		Albedo:     make([][]primitives.Color, width),
+		MaterialId: make([][]int, width),
+		ObjectId:   make([][]int, width),
+	}
+	for x := 0; x < width; x++ {
+		buffers.Depth[x] = make([]float64, height)
+		buffers.Normal[x] = make([]primitives.Vector, height)
+		buffers.Albedo[x] = make([]primitives.Color, height)
+		buffers.MaterialId[x] = make([]int, height)
+		buffers.ObjectId[x] = make([]int, height)
+		for y := 0; y < height; y++ {
+			buffers.MaterialId[x][y] = NO_OBJECT_ID
+			buffers.ObjectId[x][y] = NO_OBJECT_ID
+		}
+	}
+	return buffers
+}
+
+// recordPrimary adds the first hit of a camera ray to the pixel being rendered
+func (state *traceState) recordPrimary(ray *geometry.Ray, intersection *geometry.Intersection) {
+	if !state.aov.recorded {
+		return
+	}
+	aov := &state.aov
+	aov.samples++
+	if !intersection.Coefficient.HasIntersection {
+		return
+	}
+	normal := intersection.Object.GetNormal(intersection)
+	if normal.Dot(ray.Direction) > 0 {
+		normal = normal.Mult(-1)
+	}
+	material := intersection.Object.GetMaterial()
+	aov.depth += intersection.Point.Sub(ray.Begin).Length()
+	aov.normal = aov.normal.Add(normal)
+	aov.albedo = aov.albedo.Add(getSurfaceColor(intersection, material))
+	if aov.hits == 0 {
+		aov.materialId = material.MaterialId
+		aov.objectId = intersection.Object.GetObjectId()
+	}
+	aov.hits++
+}
+
+func (scene *Scene) storeAOVs(x, y int, aov *aovPixel) {
+	if !aov.recorded || aov.samples == 0 {
+		return
+	}
+	weight := 1 / float64(aov.samples)
+	scene.AOVBuffers.Depth[x][y] = aov.depth * weight
+	normal := aov.normal
+	if normal.Length() > 0 {
+		normal = normal.Norm()
+	}
+	scene.AOVBuffers.Normal[x][y] = normal
+	scene.AOVBuffers.Albedo[x][y] = aov.albedo.Mult(weight)
+	if aov.hits > 0 {
+		scene.AOVBuffers.MaterialId[x][y] = aov.materialId
+		scene.AOVBuffers.ObjectId[x][y] = aov.objectId
+	}
+}
+
+func (buffers *AOVBuffers) getSize() (int, int) {
+	if len(buffers.Depth) == 0 {
+		return 0, 0
+	}
+	return len(buffers.Depth), len(buffers.Depth[0])
+}
+
+// Image turns a buffer into an image. Depth is a 16-bit grey scale going from white
+// at the nearest hit to dark grey at the farthest one with a black background,
+// normals map [-1, 1] to [0, 1], ids are stored as id + 1 in 16-bit grey with 0
+// for the background and albedo is encoded by sceneFilm without exposure.
+func (buffers *AOVBuffers) Image(aov AOV, sceneFilm film.Film) (image.Image, error) {
+	width, height := buffers.getSize()
+	if buffers.Depth == nil {
+		return nil, fmt.Errorf("AOV %q was not rendered", aov)
+	}
+	gray16 := func(value func(x, y int) float64) image.Image {
+		result := image.NewGray16(image.Rect(0, 0, width, height))
+		for x := 0; x < width; x++ {
+			for y := 0; y < height; y++ {
+				result.SetGray16(x, y, color.Gray16{Y: uint16(math.Round(primitives.Clamp(0, 65535, value(x, y))))})
+			}
+		}
+		return result
+	}
+
+	switch aov {
+	case DepthAOV:
+		minDepth, maxDepth := math.Inf(1), 0.0
+		for x := range buffers.Depth {
+			for _, depth := range buffers.Depth[x] {
+				if depth > 0 {
+					minDepth, maxDepth = math.Min(minDepth, depth), math.Max(maxDepth, depth)
+				}
+			}
+		}
+		depthRange := math.Max(maxDepth-minDepth, primitives.EPS)
+		return gray16(func(x, y int) float64 {
+			depth := buffers.Depth[x][y]
+			if depth <= 0 {
+				return 0
+			}
+			return 65535 * (1 - 0.9*(depth-minDepth)/depthRange)
+		}), nil
+	case MaterialAOV:
+		return gray16(func(x, y int) float64 {
+			return float64(buffers.MaterialId[x][y] + 1)
+		}), nil
+	case ObjectAOV:
+		return gray16(func(x, y int) float64 {
+			return float64(buffers.ObjectId[x][y] + 1)
+		}), nil
+	case NormalAOV:
+		pixels := make([][]primitives.Color, width)
+		for x := range pixels {
+			pixels[x] = make([]primitives.Color, height)
+			for y := range pixels[x] {
+				normal := buffers.Normal[x][y]
+				pixels[x][y] = primitives.Color{R: normal.X, G: normal.Y, B: normal.Z}.Mult(0.5).Add(primitives.Color{R: 0.5, G: 0.5, B: 0.5})
+			}
+		}
+		aovFilm := sceneFilm
+		aovFilm.Exposure, aovFilm.ToneMapping, aovFilm.Linear = 0, "", true
+		return aovFilm.ToImage(pixels), nil
+	case AlbedoAOV:
+		aovFilm := sceneFilm
+		aovFilm.Exposure, aovFilm.ToneMapping = 0, ""
+		return aovFilm.ToImage(buffers.Albedo), nil
+	default:
+		return nil, fmt.Errorf("unknown AOV %q", aov)
+	}
+}
+
+// Channels returns the raw values of a buffer as channels of the layer named after aov,
+// ids keep NO_OBJECT_ID for the background
+func (buffers *AOVBuffers) Channels(aov AOV) ([]film.Channel, error) {
+	if buffers.Depth == nil {
+		return nil, fmt.Errorf("AOV %q was not rendered", aov)
+	}
+	width, height := buffers.getSize()
+	channel := func(name string, value func(x, y int) float64) film.Channel {
+		values := make([][]float64, width)
+		for x := range values {
+			values[x] = make([]float64, height)
+			for y := range values[x] {
+				values[x][y] = value(x, y)
+			}
+		}
+		return film.Channel{Name: string(aov) + "." + name, Values: values}
+	}
+
+	switch aov {
+	case DepthAOV:
+		return []film.Channel{channel("Z", func(x, y int) float64 {
+			return buffers.Depth[x][y]
+		})}, nil
+	case NormalAOV:
+		return []film.Channel{
+			channel("X", func(x, y int) float64 { return buffers.Normal[x][y].X }),
+			channel("Y", func(x, y int) float64 { return buffers.Normal[x][y].Y }),
+			channel("Z", func(x, y int) float64 { return buffers.Normal[x][y].Z }),
+		}, nil
+	case AlbedoAOV:
+		return film.ColorChannels(string(aov), buffers.Albedo), nil
+	case MaterialAOV:
+		return []film.Channel{channel("id", func(x, y int) float64 {
+			return float64(buffers.MaterialId[x][y])
+		})}, nil
+	case ObjectAOV:
+		return []film.Channel{channel("id", func(x, y int) float64 {
+			return float64(buffers.ObjectId[x][y])
+		})}, nil
+	default:
+		return nil, fmt.Errorf("unknown AOV %q", aov)
+	}
+}
@@ -0,0 +1,96 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"ray-tracing/primitives"
+	"ray-tracing/textures"
+)
+
+type BackgroundType string
+
+const (
+	ColorBackground       BackgroundType = "color"
+	GradientBackground    BackgroundType = "gradient"
+	EnvironmentBackground BackgroundType = "environment"
+)
+
+// Background is what rays see when they leave the scene, the default is a flat grey
+type Background struct {
+	Type BackgroundType
+	// Color fills a solid background
+	Color primitives.Color
+	// Top and Bottom are blended along the world Y axis by a gradient background
+	Top, Bottom primitives.Color
+	// Image is an equirectangular PNG or Radiance .hdr file relative to the scene file
+	Image string
+	// Intensity scales the environment image, 1 when omitted
+	Intensity float64
+
+	texture *textures.Texture
+}
+
+func (background *Background) Validate() error {
+	switch background.Type {
+	case "", ColorBackground, GradientBackground, EnvironmentBackground:
+		return nil
+	default:
+		return fmt.Errorf("unknown background type %q", background.Type)
+	}
+}
+
+// Load decodes the colours of the background from space and reads its environment image, if any
+func (background *Background) Load(directory string, space primitives.ColorSpace) error {
+	if err := background.Validate(); err != nil {
+		return err
+	}
+	background.Color = background.Color.Decode(space)
+	background.Top = background.Top.Decode(space)
+	background.Bottom = background.Bottom.Decode(space)
+
+	if background.Type != EnvironmentBackground {
+		return nil
+	}
+	if background.Image == "" {
+		return fmt.Errorf("environment background has no image")
+	}
+	texture, err := textures.LoadTexture(filepath.Join(directory, background.Image), space)
+	if err != nil {
+		return err
+	}
+	background.texture = texture
+	return nil
+}
+
+// GetColor returns the radiance coming from infinity along direction
+func (background *Background) GetColor(direction primitives.Vector) primitives.Color {
+	switch background.Type {
+	case ColorBackground:
+		return background.Color
+	case GradientBackground:
+		if !isDirection(direction) {
+			return backgroundColor
+		}
+		t := primitives.Clamp(0, 1, (direction.Y+1)/2)
+		return background.Bottom.Mult(1 - t).Add(background.Top.Mult(t))
+	case EnvironmentBackground:
+		if background.texture == nil || !isDirection(direction) {
+			return backgroundColor
+		}
+		u := 0.5 + math.Atan2(direction.Z, direction.X)/(2*math.Pi)
+		v := 1 - math.Acos(primitives.Clamp(-1, 1, direction.Y))/math.Pi
+		intensity := background.Intensity
+		if intensity == 0 {
+			intensity = 1
+		}
+		return background.texture.SampleLatLong(u, v).Mult(intensity)
+	default:
+		return backgroundColor
+	}
+}
+
+// isDirection rejects the zero and NaN vectors degenerate rays may carry
+func isDirection(direction primitives.Vector) bool {
+	return direction.SqrLength() > 0
+}
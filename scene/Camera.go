@@ -0,0 +1,188 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"ray-tracing/geometry"
+	"ray-tracing/primitives"
+)
+
+type Projection string
+
+const (
+	PerspectiveProjection     Projection = "perspective"
+	OrthographicProjection    Projection = "orthographic"
+	FisheyeProjection         Projection = "fisheye"
+	EquirectangularProjection Projection = "equirectangular"
+)
+
+// ICamera generates primary rays, x and y are continuous pixel coordinates and
+// u, v in [0, 1) sample the lens. A nil ray means the pixel sees nothing.
+type ICamera interface {
+	GenerateRay(x, y, u, v float64) *geometry.Ray
+}
+
+// Camera is a look-at description of a camera, the image size still comes from the Viewport
+type Camera struct {
+	Position, Target, Up primitives.Vector
+	// Projection defaults to perspective
+	Projection Projection
+	// Fov is the vertical field of view in degrees, for fisheye it is the full circle angle
+	Fov float64
+	// Aspect is the width to height ratio of the image plane, Width / Height when omitted
+	Aspect float64
+	// Size is the world height of the orthographic view, taken from Fov at the target when omitted
+	Size float64
+
+	// Aperture is the thin lens radius, FocusDistance defaults to the target distance
+	Aperture, FocusDistance float64
+}
+
+// OrthographicCamera shoots parallel rays through the viewport rectangle along its axis
+type OrthographicCamera struct {
+	Viewport Viewport
+}
+
+// FisheyeCamera is an equidistant fisheye, the image circle is inscribed into the picture
+type FisheyeCamera struct {
+	Position, Forward, Right, Up primitives.Vector
+	Fov                          float64
+	Width, Height                int
+}
+
+// EquirectangularCamera covers the full sphere, longitude goes along x and latitude along y
+type EquirectangularCamera struct {
+	Position, Forward, Right, Up primitives.Vector
+	Width, Height                int
+}
+
+func (camera *OrthographicCamera) GenerateRay(x, y, u, v float64) *geometry.Ray {
+	screenPoint := camera.Viewport.GetScreenPoint(x, y)
+	axis := camera.Viewport.GetAxis()
+	begin := screenPoint.Sub(axis.Mult(camera.Viewport.TopLeft.Sub(camera.Viewport.Origin).Dot(axis)))
+	return geometry.NewRay(begin, screenPoint)
+}
+
+func (camera *FisheyeCamera) GenerateRay(x, y, u, v float64) *geometry.Ray {
+	radius := math.Min(float64(camera.Width), float64(camera.Height)) / 2
+	nx := (x - float64(camera.Width)/2) / radius
+	ny := (float64(camera.Height)/2 - y) / radius
+	distance := math.Sqrt(nx*nx + ny*ny)
+	if distance > 1 {
+		return nil
+	}
+	theta := distance * camera.Fov * math.Pi / 360
+	direction := camera.Forward.Mult(math.Cos(theta))
+	if distance > 0 {
+		side := camera.Right.Mult(nx / distance).Add(camera.Up.Mult(ny / distance))
+		direction = direction.Add(side.Mult(math.Sin(theta)))
+	}
+	return geometry.NewRay(camera.Position, camera.Position.Add(direction))
+}
+
+func (camera *EquirectangularCamera) GenerateRay(x, y, u, v float64) *geometry.Ray {
+	phi := (x/float64(camera.Width) - 0.5) * 2 * math.Pi
+	theta := y / float64(camera.Height) * math.Pi
+	direction := camera.Forward.Mult(math.Sin(theta) * math.Cos(phi)).
+		Add(camera.Right.Mult(math.Sin(theta) * math.Sin(phi))).
+		Add(camera.Up.Mult(math.Cos(theta)))
+	return geometry.NewRay(camera.Position, camera.Position.Add(direction))
+}
+
+// getBasis returns the forward, right and up unit vectors of the camera
+func (camera *Camera) getBasis() (primitives.Vector, primitives.Vector, primitives.Vector, error) {
+	direction := camera.Target.Sub(camera.Position)
+	if primitives.Equal(direction.Length(), 0) {
+		return primitives.Vector{}, primitives.Vector{}, primitives.Vector{},
+			errors.New("camera target coincides with its position")
+	}
+	forward := direction.Norm()
+	up := camera.Up
+	if up == (primitives.Vector{}) {
+		up = primitives.Vector{Y: 1}
+	}
+	right := forward.Cross(up)
+	if primitives.Equal(right.Length(), 0) {
+		return primitives.Vector{}, primitives.Vector{}, primitives.Vector{},
+			errors.New("camera up vector is parallel to the view direction")
+	}
+	right = right.Norm()
+	return forward, right, right.Cross(forward), nil
+}
+
+// GetViewport places an image plane one unit in front of the camera and returns its corners
+func (camera *Camera) GetViewport(width, height int) (Viewport, error) {
+	if camera.Fov <= 0 || camera.Fov >= 180 {
+		if camera.Projection != OrthographicProjection || camera.Size <= 0 {
+			return Viewport{}, errors.New("camera field of view must be between 0 and 180 degrees")
+		}
+	}
+	forward, right, up, err := camera.getBasis()
+	if err != nil {
+		return Viewport{}, err
+	}
+	targetDistance := camera.Target.Sub(camera.Position).Length()
+
+	aspect := camera.Aspect
+	if aspect <= 0 {
+		aspect = float64(width) / float64(height)
+	}
+	halfHeight := math.Tan(camera.Fov * math.Pi / 360)
+	if camera.Projection == OrthographicProjection {
+		halfHeight *= targetDistance
+		if camera.Size > 0 {
+			halfHeight = camera.Size / 2
+		}
+	}
+	halfWidth := halfHeight * aspect
+
+	focusDistance := camera.FocusDistance
+	if focusDistance <= 0 {
+		focusDistance = targetDistance
+	}
+
+	center := camera.Position.Add(forward)
+	return Viewport{
+		Origin:     camera.Position,
+		TopLeft:    center.Add(up.Mult(halfHeight)).Sub(right.Mult(halfWidth)),
+		BottomLeft: center.Sub(up.Mult(halfHeight)).Sub(right.Mult(halfWidth)),
+		TopRight:   center.Add(up.Mult(halfHeight)).Add(right.Mult(halfWidth)),
+		Width:      width,
+		Height:     height,
+
+		Aperture:      camera.Aperture,
+		FocusDistance: focusDistance,
+	}, nil
+}
+
+// GetCamera builds the ray generator for the projection together with the viewport describing the image
+func (camera *Camera) GetCamera(width, height int) (ICamera, Viewport, error) {
+	switch camera.Projection {
+	case "", PerspectiveProjection, OrthographicProjection:
+		viewport, err := camera.GetViewport(width, height)
+		if err != nil {
+			return nil, Viewport{}, err
+		}
+		if camera.Projection == OrthographicProjection {
+			return &OrthographicCamera{viewport}, viewport, nil
+		}
+		return &viewport, viewport, nil
+	case FisheyeProjection, EquirectangularProjection:
+		forward, right, up, err := camera.getBasis()
+		if err != nil {
+			return nil, Viewport{}, err
+		}
+		viewport := Viewport{Origin: camera.Position, Width: width, Height: height}
+		if camera.Projection == EquirectangularProjection {
+			return &EquirectangularCamera{camera.Position, forward, right, up, width, height}, viewport, nil
+		}
+		fov := camera.Fov
+		if fov <= 0 {
+			fov = 180
+		}
+		return &FisheyeCamera{camera.Position, forward, right, up, fov, width, height}, viewport, nil
+	default:
+		return nil, Viewport{}, fmt.Errorf("unknown camera projection %q", camera.Projection)
+	}
+}
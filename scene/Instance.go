@@ -0,0 +1,158 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"ray-tracing/geometry"
+	"ray-tracing/kd_tree"
+	"ray-tracing/materials"
+	"ray-tracing/primitives"
+	"ray-tracing/textures"
+)
+
+// Mesh is a model and a set of primitives loaded once and placed in the scene by instances
+type Mesh struct {
+	// ModelName is an obj file relative to the scene file
+	ModelName  string
+	Shading    Shading
+	Primitives []Primitive
+}
+
+// Instance places a copy of a mesh in the scene
+type Instance struct {
+	// Mesh is the name of the mesh in SceneSerialisable.Meshes
+	Mesh      string
+	Transform Transform
+	// Motion moves the instance from its Transform during the frame
+	Motion *Motion
+	// Material replaces all the materials of the mesh when present
+	Material *PrimitiveMaterial
+}
+
+// Transform scales first, then rotates around X, Y and Z and translates last
+type Transform struct {
+	Translate primitives.Vector
+	// Rotation in degrees around every axis
+	Rotation primitives.Vector
+	// Scale along every axis, axes it omits keep their size
+	Scale primitives.Vector
+	// Matrix replaces the other fields when present, its rows are applied to column vectors
+	Matrix *primitives.Matrix4
+}
+
+func (transform *Transform) GetMatrix() primitives.Matrix4 {
+	if transform.Matrix != nil {
+		return *transform.Matrix
+	}
+	scale := transform.Scale
+	for _, factor := range []*float64{&scale.X, &scale.Y, &scale.Z} {
+		if *factor == 0 {
+			*factor = 1
+		}
+	}
+	return primitives.Translation(transform.Translate).
+		Mult(getRotationMatrix(transform.Rotation)).
+		Mult(primitives.Scaling(scale))
+}
+
+// getRotationMatrix rotates around X, then Y, then Z by the angles in degrees
+func getRotationMatrix(degrees primitives.Vector) primitives.Matrix4 {
+	toRadians := math.Pi / 180
+	return primitives.RotationZ(degrees.Z * toRadians).
+		Mult(primitives.RotationY(degrees.Y * toRadians)).
+		Mult(primitives.RotationX(degrees.X * toRadians))
+}
+
+// load builds the KD-tree shared by the instances of the mesh, its material ids are counted
+// from firstMaterialId and the number of materials it uses is returned with it
+func (mesh *Mesh) load(directory string, space primitives.ColorSpace, firstMaterialId int) (*kd_tree.KDTree, int, error) {
+	if err := mesh.Shading.Validate(); err != nil {
+		return nil, 0, err
+	}
+	var objects []geometry.IGeometryObject
+	materialCount := 0
+	if mesh.ModelName != "" {
+		model, err := loadModel(filepath.Join(directory, mesh.ModelName), space, mesh.Shading)
+		if err != nil {
+			return nil, 0, err
+		}
+		renumbered := make(map[*materials.Material]bool)
+		for _, trg := range model {
+			if material := trg.GetMaterial(); !renumbered[material] {
+				renumbered[material] = true
+				material.MaterialId += firstMaterialId
+			}
+			objects = append(objects, trg)
+		}
+		materialCount = len(renumbered)
+	}
+	analytic, err := loadPrimitives(mesh.Primitives, directory, space, 0, firstMaterialId+materialCount)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, obj := range analytic {
+		if !obj.GetBoundingBox().IsBounded() {
+			return nil, 0, fmt.Errorf("unbounded primitives cannot be instanced")
+		}
+	}
+	objects = append(objects, analytic...)
+	if len(objects) == 0 {
+		return nil, 0, fmt.Errorf("mesh is empty")
+	}
+
+	tree := new(kd_tree.KDTree)
+	tree.BuildTree(objects)
+	return tree, materialCount + len(analytic), nil
+}
+
+// loadInstances creates the instances with object ids counted from firstObjectId, meshes are
+// loaded on first use and their materials and the override materials get ids from firstMaterialId
+func loadInstances(instances []Instance, meshes map[string]Mesh, directory string, space primitives.ColorSpace,
+	firstObjectId, firstMaterialId int) ([]geometry.IGeometryObject, error) {
+	objects := make([]geometry.IGeometryObject, 0, len(instances))
+	trees := make(map[string]*kd_tree.KDTree)
+	loaded := make(map[string]*textures.Texture)
+	materialId := firstMaterialId
+	for i := range instances {
+		name := instances[i].Mesh
+		tree, ok := trees[name]
+		if !ok {
+			mesh, ok := meshes[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown mesh %q", name)
+			}
+			var materialCount int
+			var err error
+			if tree, materialCount, err = mesh.load(directory, space, materialId); err != nil {
+				return nil, fmt.Errorf("mesh %q: %w", name, err)
+			}
+			trees[name] = tree
+			materialId += materialCount
+		}
+
+		var material *materials.Material
+		if instances[i].Material != nil {
+			if err := instances[i].Material.Validate(); err != nil {
+				return nil, err
+			}
+			var err error
+			if material, err = instances[i].Material.getMaterial(materialId, directory, space, loaded); err != nil {
+				return nil, err
+			}
+			materialId++
+		}
+		start := instances[i].Transform.GetMatrix()
+		end := start
+		if instances[i].Motion != nil {
+			start, end = instances[i].Motion.getMatrices(start)
+		}
+		instance, err := geometry.NewMovingInstance(tree, start, end, material)
+		if err != nil {
+			return nil, err
+		}
+		instance.ObjectId = geometry.ObjectId(firstObjectId + i)
+		objects = append(objects, instance)
+	}
+	return objects, nil
+}
@@ -1,9 +1,25 @@
 package scene
 
 import (
+    "fmt"
+    "math"
     "ray-tracing/primitives"
 )
 
+// DIRECTIONAL_LIGHT_DISTANCE places directional lights far enough to be outside any scene
+const DIRECTIONAL_LIGHT_DISTANCE float64 = 1e7
+
+type LightType string
+
+const (
+    PointLight       LightType = "point"
+    DirectionalLight LightType = "directional"
+    SpotLight        LightType = "spot"
+    RectangleLight   LightType = "rectangle"
+    DiskLight      LightType = "disk"
+    SphereLight    LightType = "sphere"
+)
+
 type Reference struct {
     Power float64
     Distance float64
@@ -13,4 +29,130 @@ type Light struct {
     Ref      Reference
     Power    float64
     Position primitives.Vector
+    // Color tints the light, white when omitted
+    Color primitives.Color
+
+    // Type defaults to a point light when omitted
+    Type LightType
+    // Direction points where directional and spot lights shine
+    Direction primitives.Vector
+    // Angle is the half-angle of a spot cone in degrees, the outer Falloff degrees of it fade out
+    Angle, Falloff float64
+    // Edge1 and Edge2 span a rectangle light centred at Position
+    Edge1, Edge2 primitives.Vector
+    // Normal orients a disk light, Radius sizes disk and sphere lights
+    Normal primitives.Vector
+    Radius float64
+}
+
+func (light *Light) Validate() error {
+    zero := primitives.Vector{}
+    switch light.Type {
+    case "", PointLight:
+    case DirectionalLight:
+        if light.Direction == zero {
+            return fmt.Errorf("directional light needs a direction")
+        }
+    case SpotLight:
+        if light.Direction == zero {
+            return fmt.Errorf("spot light needs a direction")
+        }
+        if light.Angle <= 0 {
+            return fmt.Errorf("spot light angle must be positive")
+        }
+    case RectangleLight:
+        if light.Edge1 == zero || light.Edge2 == zero || light.Edge1.Cross(light.Edge2) == zero {
+            return fmt.Errorf("rectangle light needs two non-parallel edges")
+        }
+    case DiskLight:
+        if light.Normal == zero {
+            return fmt.Errorf("disk light needs a normal")
+        }
+        if light.Radius <= 0 {
+            return fmt.Errorf("disk light radius must be positive")
+        }
+    case SphereLight:
+        if light.Radius <= 0 {
+            return fmt.Errorf("sphere light radius must be positive")
+        }
+    default:
+        return fmt.Errorf("unknown light type %q", light.Type)
+    }
+    return nil
+}
+
+func (light *Light) IsArea() bool {
+    return light.Type == RectangleLight || light.Type == DiskLight || light.Type == SphereLight
+}
+
+func (light *Light) GetColor() primitives.Color {
+    if light.Color == (primitives.Color{}) {
+        return primitives.Color{R: 1, G: 1, B: 1}
+    }
+    return light.Color
+}
+
+// GetIntensity returns the light power reaching a point at the given squared distance
+func (light *Light) GetIntensity(sqrDistance float64) float64 {
+    power := light.Power
+    if light.Ref.Power != 0 {
+        power /= light.Ref.Distance / light.Ref.Power
+    }
+    if light.Type == DirectionalLight {
+        return power
+    }
+    return power / sqrDistance
+}
+
+// GetRadiance returns the coloured light arriving at point from lightPoint on the light
+func (light *Light) GetRadiance(point, lightPoint primitives.Vector) primitives.Color {
+    intensity := light.GetIntensity(lightPoint.Sub(point).SqrLength())
+    if light.Type == SpotLight {
+        intensity *= light.spotFactor(point.Sub(lightPoint).Norm())
+    }
+    return light.GetColor().Mult(intensity)
+}
+
+func (light *Light) spotFactor(direction primitives.Vector) float64 {
+    cosAngle := direction.Dot(light.Direction.Norm())
+    outer := math.Cos(light.Angle * math.Pi / 180)
+    inner := math.Cos(math.Max(light.Angle-light.Falloff, 0) * math.Pi / 180)
+    if primitives.GreaterEqual(outer, inner) {
+        if cosAngle >= outer {
+            return 1
+        }
+        return 0
+    }
+    t := primitives.Clamp(0, 1, (cosAngle-outer)/(inner-outer))
+    return t * t * (3 - 2*t)
+}
+
+// SamplePoint maps u, v from [0, 1) onto the light surface as seen from point.
+// The second value is the emission cosine of flat lights, which shine from both faces.
+func (light *Light) SamplePoint(point primitives.Vector, u, v float64) (primitives.Vector, float64) {
+    switch light.Type {
+    case RectangleLight:
+        lightPoint := light.Position.Add(light.Edge1.Mult(u - 0.5)).Add(light.Edge2.Mult(v - 0.5))
+        normal := light.Edge1.Cross(light.Edge2).Norm()
+        return lightPoint, math.Abs(normal.Dot(point.Sub(lightPoint).Norm()))
+    case DiskLight:
+        normal := light.Normal.Norm()
+        lightPoint := sampleDisk(light.Position, normal, light.Radius, u, v)
+        return lightPoint, math.Abs(normal.Dot(point.Sub(lightPoint).Norm()))
+    case SphereLight:
+        // the silhouette of a sphere is a disk facing the point
+        lightPoint := sampleDisk(light.Position, point.Sub(light.Position).Norm(), light.Radius, u, v)
+        return lightPoint, 1
+    case DirectionalLight:
+        return point.Sub(light.Direction.Norm().Mult(DIRECTIONAL_LIGHT_DISTANCE)), 1
+    default:
+        return light.Position, 1
+    }
+}
+
+func sampleDisk(center, normal primitives.Vector, radius, u, v float64) primitives.Vector {
+    baseU, baseV := normal.Basis()
+    r := radius * math.Sqrt(u)
+    phi := 2 * math.Pi * v
+    return center.Add(baseU.Mult(r * math.Cos(phi))).Add(baseV.Mult(r * math.Sin(phi)))
 }
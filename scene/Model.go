@@ -0,0 +1,112 @@
+package scene
+
+import (
+	"fmt"
+	"path/filepath"
+	"ray-tracing/geometry"
+	"ray-tracing/materials"
+	"ray-tracing/primitives"
+	"ray-tracing/textures"
+
+	"github.com/udhos/gwob"
+)
+
+// loadModel reads the triangles of an obj file with the materials and diffuse maps of its
+// mtllib, every obj group becomes a separate object id
+func loadModel(filename string, space primitives.ColorSpace, shading Shading) ([]*geometry.Triangle, error) {
+	options := gwob.ObjParserOptions{IgnoreNormals: shading.Normals == GeneratedNormals || shading.Normals == FlatNormals}
+	obj, err := gwob.NewObjFromFile(filename, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	mtlFilename := filepath.Join(filepath.Dir(filename), obj.Mtllib)
+	mtlib, err := gwob.ReadMaterialLibFromFile(mtlFilename, &gwob.ObjParserOptions{})
+	if err != nil {
+		return nil, err
+	}
+	opacity, err := materials.ReadOpacity(mtlFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	var faces, fileNormals, textureCoords [][3]primitives.Vector
+	var faceMaterials []*materials.Material
+	var faceObjects []int
+	groupMaterials := make(map[string]*materials.Material)
+	diffuseMaps := make(map[string]*textures.Texture)
+
+	for groupId, g := range obj.Groups {
+		material, ok := groupMaterials[g.Usemtl]
+		if !ok {
+			groupLib, ok := mtlib.Lib[g.Usemtl]
+			if !ok {
+				return nil, fmt.Errorf("material %q is not defined in %s", g.Usemtl, obj.Mtllib)
+			}
+			material = materials.NewMaterialFromMTL(groupLib, opacity[g.Usemtl], len(groupMaterials), space)
+			if groupLib.MapKd != "" {
+				mapFilename := filepath.Join(filepath.Dir(mtlFilename), groupLib.MapKd)
+				if material.DiffuseMap, ok = diffuseMaps[mapFilename]; !ok {
+					if material.DiffuseMap, err = textures.LoadTexture(mapFilename, space); err != nil {
+						return nil, err
+					}
+					diffuseMaps[mapFilename] = material.DiffuseMap
+				}
+			}
+			groupMaterials[g.Usemtl] = material
+		}
+
+		for ind := g.IndexBegin; ind < g.IndexBegin+g.IndexCount; ind += 3 {
+			var face, normals, coords [3]primitives.Vector
+			for corner := 0; corner < 3; corner++ {
+				stride := obj.Indices[ind+corner]
+				face[corner] = primitives.VectorFromFloat32(obj.VertexCoordinates(stride))
+				if obj.NormCoordFound {
+					normals[corner] = getObjNormal(obj, stride).Norm()
+				}
+				if obj.TextCoordFound {
+					coords[corner] = getObjTextureCoords(obj, stride)
+				}
+			}
+			faces = append(faces, face)
+			fileNormals = append(fileNormals, normals)
+			textureCoords = append(textureCoords, coords)
+			faceMaterials = append(faceMaterials, material)
+			faceObjects = append(faceObjects, groupId)
+		}
+	}
+
+	var vertexNormals [][3]primitives.Vector
+	switch {
+	case shading.Normals == FlatNormals:
+	case obj.NormCoordFound:
+		vertexNormals = fileNormals
+	default:
+		vertexNormals = geometry.GenerateNormals(faces, shading.getCreaseAngle())
+	}
+
+	triangles := make([]*geometry.Triangle, 0, len(faces))
+	for i, face := range faces {
+		var triangle *geometry.Triangle
+		if vertexNormals != nil {
+			triangle = geometry.NewSmoothTriangle(face, vertexNormals[i], textureCoords[i], faceMaterials[i])
+		} else {
+			triangle = geometry.NewTriangle(face, textureCoords[i], faceMaterials[i])
+		}
+		triangle.ObjectId = geometry.ObjectId(faceObjects[i])
+		triangles = append(triangles, triangle)
+	}
+	return triangles, nil
+}
+
+// getObjNormal reads the vn record stored in the stride of a vertex
+func getObjNormal(obj *gwob.Obj, stride int) primitives.Vector {
+	offset := obj.StrideOffsetNormal/4 + stride*obj.StrideSize/4
+	return primitives.VectorFromFloat32(obj.Coord[offset], obj.Coord[offset+1], obj.Coord[offset+2])
+}
+
+// getObjTextureCoords reads the vt record of a vertex as a vector with u and v in X and Y
+func getObjTextureCoords(obj *gwob.Obj, stride int) primitives.Vector {
+	offset := obj.StrideOffsetTexture/4 + stride*obj.StrideSize/4
+	return primitives.VectorFromFloat32(obj.Coord[offset], obj.Coord[offset+1], 0)
+}
@@ -0,0 +1,72 @@
+package scene
+
+import (
+	"ray-tracing/geometry"
+	"ray-tracing/kd_tree"
+	"ray-tracing/primitives"
+)
+
+// Shutter is the part of the frame, between 0 and 1, during which the camera collects light
+type Shutter struct {
+	Open, Close float64
+}
+
+// Motion moves an object from the Start transform when the frame begins to the End transform
+// when it ends. The transforms are applied after the placement of the object, translation,
+// rotation and scale are interpolated separately at the time of every ray.
+type Motion struct {
+	Start, End Transform
+}
+
+// getMatrices returns the start and end transforms of an object placed by placement
+func (motion *Motion) getMatrices(placement primitives.Matrix4) (primitives.Matrix4, primitives.Matrix4) {
+	return motion.Start.GetMatrix().Mult(placement), motion.End.GetMatrix().Mult(placement)
+}
+
+// moveMesh builds one KD-tree of objects and moves it as a whole, so the transform
+// is solved once per ray instead of once per object. The objects keep their ids.
+func (motion *Motion) moveMesh(objects []geometry.IGeometryObject) (geometry.IGeometryObject, error) {
+	tree := new(kd_tree.KDTree)
+	tree.BuildTree(objects)
+	start, end := motion.getMatrices(primitives.Identity())
+	instance, err := geometry.NewMovingInstance(tree, start, end, nil)
+	if err != nil {
+		return nil, err
+	}
+	instance.MeshObjectIds = true
+	return instance, nil
+}
+
+// move wraps a single object into a moving object when motion is present
+func (motion *Motion) move(object geometry.IGeometryObject) (geometry.IGeometryObject, error) {
+	if motion == nil {
+		return object, nil
+	}
+	start, end := motion.getMatrices(primitives.Identity())
+	return geometry.NewMovingObject(object, start, end)
+}
+
+// hasMotion tells whether the model, a primitive or an instance moves during the frame
+func (sceneData *SceneSerialisable) hasMotion() bool {
+	if sceneData.Motion != nil {
+		return true
+	}
+	for i := range sceneData.Primitives {
+		if sceneData.Primitives[i].Motion != nil {
+			return true
+		}
+	}
+	for i := range sceneData.Instances {
+		if sceneData.Instances[i].Motion != nil {
+			return true
+		}
+	}
+	for _, mesh := range sceneData.Meshes {
+		for i := range mesh.Primitives {
+			if mesh.Primitives[i].Motion != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+	"ray-tracing/geometry"
+	"ray-tracing/materials"
+	"ray-tracing/primitives"
+)
+
+// RUSSIAN_ROULETTE_DEPTH is the number of bounces every path survives before it may be terminated
+const RUSSIAN_ROULETTE_DEPTH int = 3
+
+type Integrator string
+
+const (
+	WhittedIntegrator     Integrator = "whitted"
+	PathTracingIntegrator Integrator = "path"
+)
+
+func (integrator Integrator) Validate() error {
+	switch integrator {
+	case "", WhittedIntegrator, PathTracingIntegrator:
+		return nil
+	default:
+		return fmt.Errorf("unknown integrator %q", integrator)
+	}
+}
+
+// tracePath estimates the radiance along ray with a unidirectional path tracer.
+// Diffuse bounces are cosine-weighted, lights are sampled explicitly at every
+// diffuse vertex and paths are terminated with Russian roulette.
+func (scene *Scene) tracePath(ray *geometry.Ray, state *traceState) primitives.Color {
+	var radiance primitives.Color
+	throughput := primitives.Color{R: 1, G: 1, B: 1}
+
+	for depth := 0; depth <= MAX_RAY_TRACING_DEPTH; depth++ {
+		intersection := scene.castRayKD(ray, state)
+		if depth == 0 {
+			state.recordPrimary(ray, &intersection)
+		}
+		if !intersection.Coefficient.HasIntersection {
+			return radiance.Add(throughput.MultColor(scene.Background.GetColor(ray.Direction)))
+		}
+		point := intersection.Point
+		material := intersection.Object.GetMaterial()
+		normal := intersection.Object.GetNormal(&intersection)
+
+		var direction primitives.Vector
+		diffuse, refracted := false, false
+		switch material.MaterialType {
+		case materials.ReflectDiffuse:
+			diffuse = state.rng.Float64() >= material.Reflect
+			direction = ray.GetReflectRay(point, normal).Direction
+		case materials.ReflectRefract:
+			direction = ray.GetReflectRay(point, normal).Direction
+			if state.rng.Float64() >= fresnel(ray.Direction, normal, material.Refract) {
+				if refractDirection := refract(ray, normal, material.Refract); refractDirection != (primitives.Vector{}) {
+					direction, refracted = refractDirection, true
+				}
+			}
+		case materials.Diffuse:
+			diffuse = true
+		case materials.Transparent:
+			diffuse = state.rng.Float64() < material.Alpha
+			direction, refracted = refract(ray, normal, material.Refract), true
+			if direction == (primitives.Vector{}) {
+				direction = ray.Direction
+			}
+		}
+
+		if diffuse {
+			if normal.Dot(ray.Direction) > 0 {
+				normal = normal.Mult(-1)
+			}
+			directLight, specularLight := scene.getDirectLight(point, normal, ray, material.Shininess, state)
+			surfaceColor := getSurfaceColor(&intersection, material)
+			// the cosine-weighted bounce below carries the Lambertian BRDF albedo / pi divided by its
+			// pdf cos / pi, the light sampled here is irradiance and needs the BRDF itself
+			brdf := surfaceColor.Mult(1 / math.Pi)
+			reflected := brdf.MultColor(directLight).Add(material.Specular.MultColor(specularLight))
+			radiance = radiance.Add(throughput.MultColor(reflected))
+			throughput = throughput.MultColor(surfaceColor)
+			direction = state.rng.cosineHemisphere(normal)
+		}
+
+		if depth >= RUSSIAN_ROULETTE_DEPTH {
+			survival := math.Min(1, math.Max(throughput.R, math.Max(throughput.G, throughput.B)))
+			if state.rng.Float64() >= survival {
+				break
+			}
+			throughput = throughput.Mult(1 / survival)
+		}
+		if refracted && !diffuse {
+			state.stats.RefractionRays++
+		} else {
+			state.stats.ReflectionRays++
+		}
+		ray = ray.Spawn(point, point.Add(direction))
+	}
+	return radiance
+}
@@ -0,0 +1,202 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"ray-tracing/geometry"
+	"ray-tracing/materials"
+	"ray-tracing/primitives"
+	"ray-tracing/textures"
+)
+
+type PrimitiveType string
+
+const (
+	SpherePrimitive   PrimitiveType = "sphere"
+	PlanePrimitive    PrimitiveType = "plane"
+	DiskPrimitive     PrimitiveType = "disk"
+	BoxPrimitive      PrimitiveType = "box"
+	CylinderPrimitive PrimitiveType = "cylinder"
+	ConePrimitive     PrimitiveType = "cone"
+	TorusPrimitive    PrimitiveType = "torus"
+)
+
+// Primitive is an analytic object declared in the scene file
+type Primitive struct {
+	Type PrimitiveType
+	// Center of a sphere, disk, box or torus, a point of a plane and the centre of the base of a cylinder or cone
+	Center primitives.Vector
+	// Axis is the normal of a plane or disk and the axis of a cylinder, cone or torus, +Y when omitted
+	Axis primitives.Vector
+	// Radius is the major radius of a torus
+	Radius float64
+	// MinorRadius is the radius of the tube of a torus
+	MinorRadius float64
+	// Height of a cylinder or cone
+	Height float64
+	// Size of a box along its edges
+	Size primitives.Vector
+	// Rotation of a box in degrees around X, then Y, then Z, the box is axis aligned when omitted
+	Rotation primitives.Vector
+	// Motion moves the primitive during the frame
+	Motion *Motion
+
+	Material PrimitiveMaterial
+}
+
+// PrimitiveMaterial describes the surface of a primitive like a material of an MTL file
+type PrimitiveMaterial struct {
+	Name             string
+	Color            primitives.Color
+	Reflect, Refract float64
+	// Alpha is the opacity, 1 when omitted
+	Alpha *float64
+	// Specular and Shininess drive Blinn-Phong highlights
+	Specular  primitives.Color
+	Shininess float64
+	// Texture is an image relative to the scene file multiplied with Color
+	Texture string
+}
+
+func (primitive *Primitive) Validate() error {
+	var sizes map[string]float64
+	switch primitive.Type {
+	case PlanePrimitive:
+	case SpherePrimitive, DiskPrimitive:
+		sizes = map[string]float64{"radius": primitive.Radius}
+	case BoxPrimitive:
+		sizes = map[string]float64{"size": math.Min(primitive.Size.X, math.Min(primitive.Size.Y, primitive.Size.Z))}
+	case CylinderPrimitive, ConePrimitive:
+		sizes = map[string]float64{"radius": primitive.Radius, "height": primitive.Height}
+	case TorusPrimitive:
+		sizes = map[string]float64{"radius": primitive.Radius, "minor radius": primitive.MinorRadius}
+	default:
+		return fmt.Errorf("unknown primitive type %q", primitive.Type)
+	}
+	for name, value := range sizes {
+		if value <= 0 {
+			return fmt.Errorf("%s %s must be positive", primitive.Type, name)
+		}
+	}
+	if err := primitive.Material.Validate(); err != nil {
+		return fmt.Errorf("%s %w", primitive.Type, err)
+	}
+	return nil
+}
+
+func (material *PrimitiveMaterial) Validate() error {
+	if material.Alpha != nil && (*material.Alpha < 0 || *material.Alpha > 1) {
+		return fmt.Errorf("alpha must be in [0, 1]")
+	}
+	return nil
+}
+
+func (primitive *Primitive) getAxis() primitives.Vector {
+	if primitive.Axis == (primitives.Vector{}) {
+		return primitives.Vector{Y: 1}
+	}
+	return primitive.Axis.Norm()
+}
+
+// getBoxAxes rotates the coordinate axes by Rotation
+func (primitive *Primitive) getBoxAxes() [3]primitives.Vector {
+	rotation := getRotationMatrix(primitive.Rotation)
+	return [3]primitives.Vector{
+		rotation.TransformDirection(primitives.Vector{X: 1}),
+		rotation.TransformDirection(primitives.Vector{Y: 1}),
+		rotation.TransformDirection(primitives.Vector{Z: 1}),
+	}
+}
+
+// primitiveObject is implemented by the geometry of every primitive type
+type primitiveObject interface {
+	geometry.IGeometryObject
+	SetObjectId(id int)
+}
+
+// getObject creates the geometry of the primitive
+func (primitive *Primitive) getObject(material *materials.Material) primitiveObject {
+	switch primitive.Type {
+	case SpherePrimitive:
+		return geometry.NewSphere(primitive.Center, primitive.Radius, material)
+	case PlanePrimitive:
+		return geometry.NewPlane(primitive.Center, primitive.getAxis(), material)
+	case DiskPrimitive:
+		return geometry.NewDisk(primitive.Center, primitive.getAxis(), primitive.Radius, material)
+	case BoxPrimitive:
+		if primitive.Rotation == (primitives.Vector{}) {
+			return geometry.NewBox(primitive.Center, primitive.Size, material)
+		}
+		return geometry.NewOrientedBox(primitive.Center, primitive.Size, primitive.getBoxAxes(), material)
+	case CylinderPrimitive:
+		return geometry.NewCylinder(primitive.Center, primitive.getAxis(), primitive.Radius, primitive.Height, material)
+	case ConePrimitive:
+		return geometry.NewCone(primitive.Center, primitive.getAxis(), primitive.Radius, primitive.Height, material)
+	case TorusPrimitive:
+		return geometry.NewTorus(primitive.Center, primitive.getAxis(), primitive.Radius, primitive.MinorRadius, material)
+	default:
+		panic("unknown primitive type " + string(primitive.Type))
+	}
+}
+
+// getMaterial decodes the colours from space and reads the texture relative to directory,
+// textures are shared with the other primitives through loaded
+func (material *PrimitiveMaterial) getMaterial(materialId int, directory string, space primitives.ColorSpace,
+	loaded map[string]*textures.Texture) (*materials.Material, error) {
+	alpha := 1.0
+	if material.Alpha != nil {
+		alpha = *material.Alpha
+	}
+	refract := material.Refract
+	if !primitives.Equal(alpha, 1) && primitives.LessEqual(refract, 0) {
+		// transparent materials always need an index of refraction
+		refract = 1
+	}
+	var name *string
+	if material.Name != "" {
+		name = &material.Name
+	}
+
+	result := materials.NewMaterial(material.Color.Decode(space), material.Reflect, refract, alpha, materialId, name)
+	result.Specular = material.Specular.Decode(space)
+	result.Shininess = material.Shininess
+	if material.Texture != "" {
+		textureFilename := filepath.Join(directory, material.Texture)
+		texture, ok := loaded[textureFilename]
+		if !ok {
+			var err error
+			if texture, err = textures.LoadTexture(textureFilename, space); err != nil {
+				return nil, err
+			}
+			loaded[textureFilename] = texture
+		}
+		result.DiffuseMap = texture
+	}
+	return result, nil
+}
+
+// loadPrimitives creates the objects of the primitives, each of them gets its own object id
+// counted from firstObjectId and material id counted from firstMaterialId
+func loadPrimitives(list []Primitive, directory string, space primitives.ColorSpace,
+	firstObjectId, firstMaterialId int) ([]geometry.IGeometryObject, error) {
+	objects := make([]geometry.IGeometryObject, 0, len(list))
+	loaded := make(map[string]*textures.Texture)
+	for i := range list {
+		if err := list[i].Validate(); err != nil {
+			return nil, err
+		}
+		material, err := list[i].Material.getMaterial(firstMaterialId+i, directory, space, loaded)
+		if err != nil {
+			return nil, err
+		}
+		object := list[i].getObject(material)
+		object.SetObjectId(firstObjectId + i)
+		moved, err := list[i].Motion.move(object)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", list[i].Type, err)
+		}
+		objects = append(objects, moved)
+	}
+	return objects, nil
+}
@@ -0,0 +1,77 @@
+package scene
+
+import (
+	"context"
+	"errors"
+	"ray-tracing/primitives"
+	"sync/atomic"
+	"time"
+)
+
+// Progressive renders the image in passes of a few samples per pixel and
+// averages them, so a usable estimate is available after the first pass
+type Progressive struct {
+	Enabled bool
+	// Passes to render, 0 keeps refining until the render is cancelled
+	Passes int
+	// SamplesPerPass is 1 when omitted
+	SamplesPerPass int
+	// SnapshotSeconds is the minimal time between two snapshots, 0 takes one after every pass
+	SnapshotSeconds float64
+}
+
+// SnapshotFunc is called between progressive passes with the number of finished passes,
+// Pixels hold the current estimate while it runs. Returning an error stops the render.
+type SnapshotFunc func(passes int) error
+
+// renderProgressive accumulates passes over the tiles covering pixels pixels until Progressive.Passes
+// are done or ctx is cancelled. Cancelling after the first pass is a normal way to stop and is not reported as an error.
+func (scene *Scene) renderProgressive(ctx context.Context, tiles []Tile, pixels int) error {
+	width, height := scene.Viewport.Width, scene.Viewport.Height
+	scene.accumulation = make([][]primitives.Color, width)
+	for x := range scene.accumulation {
+		scene.accumulation[x] = make([]primitives.Color, height)
+	}
+	defer func() {
+		scene.accumulation = nil
+	}()
+
+	samples := scene.Progressive.SamplesPerPass
+	if samples <= 0 {
+		samples = 1
+	}
+	lastSnapshot := time.Now()
+	for pass := 0; scene.Progressive.Passes <= 0 || pass < scene.Progressive.Passes; pass++ {
+		scene.accumulatedPasses = pass
+		atomic.AddInt64(&scene.pixelsTotal, int64(pixels))
+		err := scene.renderPass(ctx, tiles, func(x, y int) (renderInput, bool) {
+			return renderInput{x, y, true, samples, pass}, true
+		})
+		if err != nil {
+			if pass > 0 && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+				return nil
+			}
+			return err
+		}
+
+		if scene.OnSnapshot != nil && time.Since(lastSnapshot).Seconds() >= scene.Progressive.SnapshotSeconds {
+			if err := scene.OnSnapshot(pass + 1); err != nil {
+				return err
+			}
+			lastSnapshot = time.Now()
+		}
+	}
+	return nil
+}
+
+// storeResult writes a finished pixel, averaging it with the previous passes in progressive mode
+func (scene *Scene) storeResult(result renderResult) {
+	scene.storeAOVs(result.x, result.y, &result.aov)
+	if scene.accumulation == nil {
+		scene.Pixels[result.x][result.y] = result.color
+		return
+	}
+	sum := scene.accumulation[result.x][result.y].Add(result.color)
+	scene.accumulation[result.x][result.y] = sum
+	scene.Pixels[result.x][result.y] = sum.Mult(1 / float64(scene.accumulatedPasses+1))
+}
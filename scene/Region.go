@@ -0,0 +1,113 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+	"ray-tracing/primitives"
+)
+
+// Region restricts the render to a rectangle of the viewport. Pixels outside of it are
+// left blank, Crop makes the output only as large as the region.
+type Region struct {
+	X, Y, Width, Height int
+	Crop                bool
+}
+
+func (region *Region) Validate(width, height int) error {
+	if region.Width <= 0 || region.Height <= 0 || region.X < 0 || region.Y < 0 ||
+		region.X+region.Width > width || region.Y+region.Height > height {
+		return fmt.Errorf("region %dx%d at %d,%d does not fit into the %dx%d viewport",
+			region.Width, region.Height, region.X, region.Y, width, height)
+	}
+	return nil
+}
+
+// getRegion returns the rendered rectangle, the whole viewport when no region is set
+func (scene *Scene) getRegion() (Tile, error) {
+	width, height := scene.Viewport.Width, scene.Viewport.Height
+	if scene.Region == nil {
+		return Tile{0, 0, width, height}, nil
+	}
+	if err := scene.Region.Validate(width, height); err != nil {
+		return Tile{}, err
+	}
+	region := scene.Region
+	return Tile{region.X, region.Y, region.X + region.Width, region.Y + region.Height}, nil
+}
+
+// grow extends the tile by margin pixels on every side without leaving the image
+func (tile Tile) grow(margin, width, height int) Tile {
+	return Tile{
+		X0: int(math.Max(float64(tile.X0-margin), 0)),
+		Y0: int(math.Max(float64(tile.Y0-margin), 0)),
+		X1: int(math.Min(float64(tile.X1+margin), float64(width))),
+		Y1: int(math.Min(float64(tile.Y1+margin), float64(height))),
+	}
+}
+
+func (tile Tile) getSize() int {
+	return (tile.X1 - tile.X0) * (tile.Y1 - tile.Y0)
+}
+
+// clipTiles intersects every tile with area and drops the empty ones
+func clipTiles(tiles []Tile, area Tile) []Tile {
+	clipped := make([]Tile, 0, len(tiles))
+	for _, tile := range tiles {
+		tile = Tile{
+			X0: int(math.Max(float64(tile.X0), float64(area.X0))),
+			Y0: int(math.Max(float64(tile.Y0), float64(area.Y0))),
+			X1: int(math.Min(float64(tile.X1), float64(area.X1))),
+			Y1: int(math.Min(float64(tile.Y1), float64(area.Y1))),
+		}
+		if tile.X0 < tile.X1 && tile.Y0 < tile.Y1 {
+			clipped = append(clipped, tile)
+		}
+	}
+	return clipped
+}
+
+// clearOutside blanks the pixels and AOVs around region, e.g. the margin rendered for
+// the adaptive antialiasing pass or the leftovers of an earlier render
+func (scene *Scene) clearOutside(region Tile) {
+	for x := range scene.Pixels {
+		for y := range scene.Pixels[x] {
+			if x >= region.X0 && x < region.X1 && y >= region.Y0 && y < region.Y1 {
+				continue
+			}
+			scene.Pixels[x][y] = primitives.Color{}
+			if scene.AOVBuffers.Depth != nil {
+				scene.storeAOVs(x, y, &aovPixel{recorded: true, samples: 1})
+			}
+		}
+	}
+}
+
+func cropColumns[T any](columns [][]T, region Tile) [][]T {
+	if columns == nil {
+		return nil
+	}
+	cropped := make([][]T, 0, region.X1-region.X0)
+	for x := region.X0; x < region.X1; x++ {
+		cropped = append(cropped, columns[x][region.Y0:region.Y1])
+	}
+	return cropped
+}
+
+// Output returns the pixels and AOVs to save, cropped to the region when Region.Crop is set.
+// The cropped buffers share their memory with the scene.
+func (scene *Scene) Output() ([][]primitives.Color, *AOVBuffers) {
+	if scene.Region == nil || !scene.Region.Crop {
+		return scene.Pixels, &scene.AOVBuffers
+	}
+	region, err := scene.getRegion()
+	if err != nil {
+		return scene.Pixels, &scene.AOVBuffers
+	}
+	return cropColumns(scene.Pixels, region), &AOVBuffers{
+		Depth:      cropColumns(scene.AOVBuffers.Depth, region),
+		Normal:     cropColumns(scene.AOVBuffers.Normal, region),
+		Albedo:     cropColumns(scene.AOVBuffers.Albedo, region),
+		MaterialId: cropColumns(scene.AOVBuffers.MaterialId, region),
+		ObjectId:   cropColumns(scene.AOVBuffers.ObjectId, region),
+	}
+}
@@ -0,0 +1,113 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+	"ray-tracing/primitives"
+)
+
+type SamplePattern string
+
+const (
+	// JitteredPattern places every sample uniformly at random inside the pixel
+	JitteredPattern SamplePattern = "jittered"
+	// StratifiedPattern splits the pixel into a grid and jitters one sample inside each cell
+	StratifiedPattern SamplePattern = "stratified"
+)
+
+// AdaptiveAntialiasing re-renders only the pixels lying on colour edges
+type AdaptiveAntialiasing struct {
+	Enabled   bool
+	Threshold float64
+	// Samples are taken in addition to the first pass ones and averaged with them
+	Samples int
+}
+
+type Antialiasing struct {
+	Samples  int
+	Pattern  SamplePattern
+	Adaptive AdaptiveAntialiasing
+}
+
+func (aa *Antialiasing) Validate() error {
+	switch aa.Pattern {
+	case "", JitteredPattern, StratifiedPattern:
+		return nil
+	default:
+		return fmt.Errorf("unknown sample pattern %q", aa.Pattern)
+	}
+}
+
+func (aa Antialiasing) adaptiveSettings() (float64, int) {
+	threshold, samples := aa.Adaptive.Threshold, aa.Adaptive.Samples
+	if threshold <= 0 {
+		threshold = ANTIALIASING_CONST
+	}
+	if samples <= 0 {
+		samples = ANTIALIASING_POINT_COUNT
+	}
+	return threshold, samples
+}
+
+// sampler is a small deterministic splitmix64 generator, seeded per pixel so
+// the same pixel always receives the same sample positions
+type sampler struct {
+	state uint64
+}
+
+func newSampler(x, y, pass int) *sampler {
+	seed := uint64(x)*0x9E3779B97F4A7C15 ^ uint64(y)*0xC2B2AE3D27D4EB4F ^ uint64(pass)*0x165667B19E3779F9
+	return &sampler{state: seed}
+}
+
+func (s *sampler) next() uint64 {
+	s.state += 0x9E3779B97F4A7C15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// Float64 returns a pseudo-random number in [0, 1)
+func (s *sampler) Float64() float64 {
+	return float64(s.next()>>11) / (1 << 53)
+}
+
+// pixelOffsets returns count sample positions inside the unit pixel square. The stratified
+// pattern fills every cell of the largest grid with at most count cells and jitters the
+// samples left over across the whole square, so no part of the pixel is favoured.
+func (s *sampler) pixelOffsets(count int, pattern SamplePattern) [][2]float64 {
+	offsets := make([][2]float64, 0, count)
+	switch pattern {
+	case JitteredPattern:
+		for i := 0; i < count; i++ {
+			offsets = append(offsets, [2]float64{s.Float64(), s.Float64()})
+		}
+	default:
+		rows := int(math.Sqrt(float64(count)))
+		if rows == 0 {
+			return offsets
+		}
+		columns := count / rows
+		for i := 0; i < rows*columns; i++ {
+			column, row := i%columns, i/columns
+			offsets = append(offsets, [2]float64{
+				(float64(column) + s.Float64()) / float64(columns),
+				(float64(row) + s.Float64()) / float64(rows),
+			})
+		}
+		for i := rows * columns; i < count; i++ {
+			offsets = append(offsets, [2]float64{s.Float64(), s.Float64()})
+		}
+	}
+	return offsets
+}
+
+// cosineHemisphere returns a direction around normal distributed proportionally to the cosine
+func (s *sampler) cosineHemisphere(normal primitives.Vector) primitives.Vector {
+	u, v := normal.Basis()
+	r := math.Sqrt(s.Float64())
+	phi := 2 * math.Pi * s.Float64()
+	z := math.Sqrt(math.Max(0, 1-r*r))
+	return u.Mult(r * math.Cos(phi)).Add(v.Mult(r * math.Sin(phi))).Add(normal.Mult(z))
+}
@@ -1,28 +1,64 @@
 package scene
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"math"
 	"path/filepath"
+	"ray-tracing/film"
 	"ray-tracing/geometry"
 	"ray-tracing/kd_tree"
 	"ray-tracing/materials"
 	"ray-tracing/primitives"
-	"sync"
 	"sync/atomic"
-
-	"github.com/udhos/gwob"
+	"time"
 )
 
 const ANTIALIASING_CONST float64 = 0.2
 const ANTIALIASING_POINT_COUNT int = 5
 const MAX_RAY_TRACING_DEPTH int = 10
+const DEFAULT_SHADOW_SAMPLES int = 16
+
+var backgroundColor = primitives.Color{R: 0.2, G: 0.2, B: 0.2}.Decode(primitives.SRGBColorSpace)
+var ambientLight = primitives.Color{R: 0.2, G: 0.2, B: 0.2}.Decode(primitives.SRGBColorSpace)
 
 type SceneSerialisable struct {
 	Lights    []Light
 	Viewport  Viewport
 	ModelName string
+
+	// Primitives are analytic objects added next to the model, ModelName may be omitted when they are given
+	Primitives []Primitive
+	// Meshes are loaded once and placed by Instances
+	Meshes    map[string]Mesh
+	Instances []Instance
+
+	// ColorSpace of the colours and images given in the scene and material files, sRGB when omitted
+	ColorSpace primitives.ColorSpace
+
+	// Camera replaces the viewport corners when present
+	Camera *Camera
+	// Shutter is the exposure interval, the whole frame when anything moves and it is omitted
+	Shutter Shutter
+	// Motion moves the model during the frame, primitives and instances have their own
+	Motion *Motion
+	// Shading selects between flat, file and generated vertex normals
+	Shading Shading
+
+	Antialiasing Antialiasing
+	Integrator   Integrator
+
+	ShadowSamples int
+	Background    Background
+	Film          film.Film
+	Scheduler     Scheduler
+	Progressive   Progressive
+	// AOVs lists the extra buffers rendered along with the image
+	AOVs []AOV
+	// Region renders only a part of the viewport when present
+	Region *Region
 }
 
 type Scene struct {
@@ -30,16 +66,40 @@ type Scene struct {
 	KDTree   *kd_tree.KDTree
 	Lights   []Light
 	Viewport Viewport
-
+	Camera   ICamera
+	Shutter  Shutter
+
+	Antialiasing  Antialiasing
+	Integrator    Integrator
+	ShadowSamples int
+	Background    Background
+	Film          film.Film
+	Scheduler     Scheduler
+	Progressive   Progressive
+	// OnSnapshot is called between progressive passes when set
+	OnSnapshot SnapshotFunc
+
+	// Pixels hold linear radiance, Film turns them into an image
 	Pixels [][]primitives.Color
 
-	Wg         sync.WaitGroup
-	RaysCasted atomic.Value
+	AOVs       []AOV
+	AOVBuffers AOVBuffers
+	Region     *Region
+
+	// unbounded objects cannot be put in the KD-tree and are tested against every ray
+	unbounded []geometry.IGeometryObject
+
+	stats                   Statistics
+	pixelsDone, pixelsTotal int64
+
+	accumulation      [][]primitives.Color
+	accumulatedPasses int
 }
 
 type renderInput struct {
-	x, y         int
-	antialiasing bool
+	x, y          int
+	antialiasing  bool
+	samples, pass int
 }
 
 func OpenScene(filename string) (*Scene, error) {
@@ -52,46 +112,114 @@ func OpenScene(filename string) (*Scene, error) {
 	if err != nil {
 		return nil, err
 	}
-	options := gwob.ObjParserOptions{IgnoreNormals: true}
-	obj, err := gwob.NewObjFromFile(filepath.Join(filepath.Dir(filename), sceneData.ModelName), &options)
+	var camera ICamera
+	if sceneData.Camera != nil {
+		camera, sceneData.Viewport, err = sceneData.Camera.GetCamera(sceneData.Viewport.Width, sceneData.Viewport.Height)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := sceneData.ColorSpace.Validate(); err != nil {
+		return nil, err
+	}
+	if err := sceneData.Integrator.Validate(); err != nil {
+		return nil, err
+	}
+	if err := sceneData.Antialiasing.Validate(); err != nil {
+		return nil, err
+	}
+	if err := sceneData.Film.Validate(); err != nil {
+		return nil, err
+	}
+	if err := sceneData.Scheduler.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ValidateAOVs(sceneData.AOVs); err != nil {
+		return nil, err
+	}
+	if err := sceneData.Shading.Validate(); err != nil {
+		return nil, err
+	}
+	for i := range sceneData.Lights {
+		if err := sceneData.Lights[i].Validate(); err != nil {
+			return nil, err
+		}
+		sceneData.Lights[i].Color = sceneData.Lights[i].Color.Decode(sceneData.ColorSpace)
+	}
+	if err := sceneData.Background.Load(filepath.Dir(filename), sceneData.ColorSpace); err != nil {
+		return nil, err
+	}
+	var model []*geometry.Triangle
+	if sceneData.ModelName != "" {
+		model, err = loadModel(filepath.Join(filepath.Dir(filename), sceneData.ModelName), sceneData.ColorSpace, sceneData.Shading)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(sceneData.Primitives) == 0 && len(sceneData.Instances) == 0 {
+		return nil, errors.New("scene has neither a model, primitives nor instances")
+	}
+	objects := make([]geometry.IGeometryObject, 0, len(model)+len(sceneData.Primitives)+len(sceneData.Instances))
+	objectCount, materialCount := 0, 0
+	for _, trg := range model {
+		objectCount = int(math.Max(float64(objectCount), float64(trg.GetObjectId()+1)))
+		materialCount = int(math.Max(float64(materialCount), float64(trg.GetMaterial().MaterialId+1)))
+		objects = append(objects, trg)
+	}
+	if sceneData.Motion != nil && len(objects) > 0 {
+		// the KD-tree of the model keeps the slice it is built from
+		movingModel, err := sceneData.Motion.moveMesh(append([]geometry.IGeometryObject(nil), objects...))
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects[:0], movingModel)
+	}
+	analytic, err := loadPrimitives(sceneData.Primitives, filepath.Dir(filename), sceneData.ColorSpace, objectCount, materialCount)
 	if err != nil {
 		return nil, err
 	}
-
-	mtlib, err := gwob.ReadMaterialLibFromFile(filepath.Join(filepath.Dir(filename), obj.Mtllib), &gwob.ObjParserOptions{})
+	objects = append(objects, analytic...)
+	objectCount += len(analytic)
+	materialCount += len(analytic)
+	instances, err := loadInstances(sceneData.Instances, sceneData.Meshes, filepath.Dir(filename), sceneData.ColorSpace,
+		objectCount, materialCount)
 	if err != nil {
 		return nil, err
 	}
+	objects = append(objects, instances...)
 
-	triangles := make([]geometry.IGeometryObject, 0)
-
-	for _, g := range obj.Groups {
-		group_lib := mtlib.Lib[g.Usemtl]
-		// Incorrect, but fast for simple use
-		material := materials.NewMaterial(
-			primitives.Color{
-				R: float64(group_lib.Kd[0]),
-				G: float64(group_lib.Kd[1]),
-				B: float64(group_lib.Kd[2]),
-			}, 0, 0, 1, 0, &group_lib.Name,
-		)
-
-		for ind := g.IndexBegin; ind < g.IndexBegin+g.IndexCount; ind += 3 {
-			v1 := primitives.VectorFromFloat32(obj.VertexCoordinates(obj.Indices[ind]))
-			v2 := primitives.VectorFromFloat32(obj.VertexCoordinates(obj.Indices[ind+1]))
-			v3 := primitives.VectorFromFloat32(obj.VertexCoordinates(obj.Indices[ind+2]))
-			triangle := geometry.NewTriangle([3]primitives.Vector{v1, v2, v3}, [3]primitives.Vector{}, material)
-			triangles = append(triangles, triangle)
-		}
+	scene := NewScene(objects, sceneData.Lights, sceneData.Viewport)
+	if camera != nil {
+		scene.Camera = camera
 	}
-
-	return NewScene(triangles, sceneData.Lights, sceneData.Viewport), nil
+	scene.Shutter = sceneData.Shutter
+	if sceneData.hasMotion() && scene.Shutter == (Shutter{}) {
+		scene.Shutter = Shutter{Open: 0, Close: 1}
+	}
+	scene.Antialiasing = sceneData.Antialiasing
+	scene.Integrator = sceneData.Integrator
+	scene.ShadowSamples = sceneData.ShadowSamples
+	scene.Background = sceneData.Background
+	scene.Film = sceneData.Film
+	scene.Scheduler = sceneData.Scheduler
+	scene.Progressive = sceneData.Progressive
+	scene.AOVs = sceneData.AOVs
+	scene.Region = sceneData.Region
+	return scene, nil
 }
 
 func NewScene(objects []geometry.IGeometryObject, lights []Light, viewport Viewport) *Scene {
 	scene := Scene{objects: objects, Lights: lights, Viewport: viewport}
+	scene.Camera = &scene.Viewport
+	bounded := make([]geometry.IGeometryObject, 0, len(objects))
+	for _, obj := range objects {
+		if obj.GetBoundingBox().IsBounded() {
+			bounded = append(bounded, obj)
+		} else {
+			scene.unbounded = append(scene.unbounded, obj)
+		}
+	}
 	scene.KDTree = new(kd_tree.KDTree)
-	scene.KDTree.BuildTree(objects)
+	scene.KDTree.BuildTree(bounded)
 	scene.Pixels = make([][]primitives.Color, viewport.Width)
 	for ind := 0; ind < viewport.Width; ind++ {
 		scene.Pixels[ind] = make([]primitives.Color, viewport.Height)
@@ -99,69 +227,204 @@ func NewScene(objects []geometry.IGeometryObject, lights []Light, viewport Viewp
 	return &scene
 }
 
-func (scene *Scene) Render() {
-	scene.Wg.Add(16)
+// Render fills Pixels and blocks until the image is done. It stops early and
+// returns the context error when ctx is cancelled, the pixels are then only partially rendered.
+// Progressive renders stopped after their first pass keep a complete, noisier image instead.
+func (scene *Scene) Render(ctx context.Context) error {
+	if scene.Viewport.Width <= 0 || scene.Viewport.Height <= 0 {
+		return errors.New("viewport must be at least one pixel wide and high")
+	}
+	if scene.Camera == nil {
+		return errors.New("scene has no camera")
+	}
+	region, err := scene.getRegion()
+	if err != nil {
+		return err
+	}
+
+	renderBegin := time.Now()
+	scene.stats = Statistics{BuildSeconds: scene.KDTree.TotalBuildingTime.Seconds()}
+	defer func() {
+		scene.stats.RenderSeconds = time.Since(renderBegin).Seconds()
+	}()
+	atomic.StoreInt64(&scene.pixelsDone, 0)
+	atomic.StoreInt64(&scene.pixelsTotal, 0)
+
+	if len(scene.AOVs) > 0 {
+		scene.AOVBuffers = newAOVBuffers(scene.Viewport.Width, scene.Viewport.Height)
+	}
 
-	inputChannel := make(chan renderInput)
+	// blank what earlier renders left around the region now and the margin traced for it later
+	scene.clearOutside(region)
+	defer scene.clearOutside(region)
 
-	for i := 0; i < 16; i++ {
-		go renderWorker(scene, inputChannel)
+	tiles := scene.Scheduler.makeTiles(scene.Viewport.Width, scene.Viewport.Height)
+	if scene.Progressive.Enabled {
+		return scene.renderProgressive(ctx, clipTiles(tiles, region), region.getSize())
 	}
 
-	for x := 0; x < scene.Viewport.Width; x++ {
-		for y := 0; y < scene.Viewport.Height; y++ {
-			inputChannel <- renderInput{x, y, false}
+	// the edge detection of the adaptive pass looks at the neighbours of every pixel,
+	// so a region render traces one more pixel around it to match the full render
+	area := region
+	if scene.Antialiasing.Adaptive.Enabled {
+		area = region.grow(1, scene.Viewport.Width, scene.Viewport.Height)
+	}
+	atomic.AddInt64(&scene.pixelsTotal, int64(area.getSize()))
+	samples := scene.Antialiasing.Samples
+	err = scene.renderPass(ctx, clipTiles(tiles, area), func(x, y int) (renderInput, bool) {
+		return renderInput{x, y, samples > 1, samples, 0}, true
+	})
+	if err != nil {
+		return err
+	}
+
+	if scene.Antialiasing.Adaptive.Enabled {
+		threshold, extraSamples := scene.Antialiasing.adaptiveSettings()
+		edges, count := scene.findEdgePixels(threshold, area, region)
+		firstPass := scene.copyEdgePixels(edges, region)
+		atomic.AddInt64(&scene.pixelsTotal, int64(count))
+		err = scene.renderPass(ctx, clipTiles(tiles, region), func(x, y int) (renderInput, bool) {
+			return renderInput{x, y, true, extraSamples, 1}, edges[x][y]
+		})
+		// pixels the cancelled pass did not reach are averaged with themselves and keep their value
+		scene.mergeEdgePixels(firstPass, int(math.Max(float64(samples), 1)), extraSamples)
+		return err
+	}
+	return nil
+}
+
+// copyEdgePixels keeps the first pass estimate of the edge pixels lying in region
+func (scene *Scene) copyEdgePixels(edges [][]bool, region Tile) map[[2]int]primitives.Color {
+	firstPass := make(map[[2]int]primitives.Color)
+	for x := region.X0; x < region.X1; x++ {
+		for y := region.Y0; y < region.Y1; y++ {
+			if edges[x][y] {
+				firstPass[[2]int{x, y}] = scene.Pixels[x][y]
+			}
 		}
 	}
-	close(inputChannel)
+	return firstPass
+}
+
+// mergeEdgePixels averages the extra samples of the adaptive pass with the first pass estimate,
+// both weighted by their sample counts, so edge pixels never end up with fewer samples than the rest
+func (scene *Scene) mergeEdgePixels(firstPass map[[2]int]primitives.Color, baseSamples, extraSamples int) {
+	total := float64(baseSamples + extraSamples)
+	for pixel, color := range firstPass {
+		extra := scene.Pixels[pixel[0]][pixel[1]]
+		scene.Pixels[pixel[0]][pixel[1]] = color.Mult(float64(baseSamples) / total).Add(extra.Mult(float64(extraSamples) / total))
+	}
+}
+
+// findEdgePixels compares the pixels of area with their neighbours inside it, marks the ones
+// whose colour differs by more than threshold and counts the marked pixels lying in region
+func (scene *Scene) findEdgePixels(threshold float64, area Tile, region Tile) ([][]bool, int) {
+	width, height := scene.Viewport.Width, scene.Viewport.Height
+	edges := make([][]bool, width)
+	for x := range edges {
+		edges[x] = make([]bool, height)
+	}
+	for x := area.X0; x < area.X1; x++ {
+		for y := area.Y0; y < area.Y1; y++ {
+			if x+1 < area.X1 && scene.Pixels[x][y].L1Norm(scene.Pixels[x+1][y]) > threshold {
+				edges[x][y], edges[x+1][y] = true, true
+			}
+			if y+1 < area.Y1 && scene.Pixels[x][y].L1Norm(scene.Pixels[x][y+1]) > threshold {
+				edges[x][y], edges[x][y+1] = true, true
+			}
+		}
+	}
+	count := 0
+	for x := region.X0; x < region.X1; x++ {
+		for y := region.Y0; y < region.Y1; y++ {
+			if edges[x][y] {
+				count++
+			}
+		}
+	}
+	return edges, count
 }
 
 func (scene *Scene) GetPixels() [][]primitives.Color {
 	return scene.Pixels
 }
 
-func renderWorker(scene *Scene, input chan renderInput) {
-	base_w := scene.Viewport.GetWidthBase().Div(float64(scene.Viewport.Width))
-	base_h := scene.Viewport.GetHeightBase().Div(float64(scene.Viewport.Height))
-	origin := scene.Viewport.Origin
-	defaultOffset := base_w.Div(2.0).Add(base_h.Div(2))
+func (scene *Scene) renderPixel(obj renderInput, state *traceState) primitives.Color {
+	state.rng = newSampler(obj.x, obj.y, obj.pass)
+	// progressive passes after the first one keep the AOVs they already have
+	state.aov = aovPixel{recorded: len(scene.AOVs) > 0 && (scene.accumulation == nil || obj.pass == 0)}
+	if !obj.antialiasing {
+		return scene.tracePixelSample(float64(obj.x)+0.5, float64(obj.y)+0.5, state)
+	}
+	return scene.samplePixel(obj, state)
+}
 
+// samplePixel averages obj.samples rays spread over the pixel footprint
+func (scene *Scene) samplePixel(obj renderInput, state *traceState) primitives.Color {
 	var color primitives.Color
+	for _, offset := range state.rng.pixelOffsets(obj.samples, scene.Antialiasing.Pattern) {
+		color = color.Add(scene.tracePixelSample(float64(obj.x)+offset[0], float64(obj.y)+offset[1], state))
+	}
+	return color.Mult(1 / float64(obj.samples))
+}
 
-	count := 0
-	for obj := range input {
-		basePoint := scene.Viewport.TopLeft.Add(base_w.Mult(float64(obj.x)).Add(base_h.Mult(float64(obj.y))))
-		if !obj.antialiasing {
-			screenPoint := basePoint.Add(defaultOffset)
-			newRay := geometry.NewRay(origin, screenPoint)
-			color = scene.traceRay(newRay)
-		}
-		scene.Pixels[obj.x][obj.y] = color
-		count++
+// tracePixelSample traces the camera ray through the continuous pixel coordinates x, y
+func (scene *Scene) tracePixelSample(x, y float64, state *traceState) primitives.Color {
+	newRay := scene.Camera.GenerateRay(x, y, state.rng.Float64(), state.rng.Float64())
+	if newRay == nil {
+		return primitives.Color{}
 	}
-	//fmt.Printf("Done after %v\n", count)
-	scene.Wg.Done()
+	state.stats.PrimaryRays++
+	if scene.Shutter.Close > scene.Shutter.Open {
+		newRay.Time = scene.Shutter.Open + state.rng.Float64()*(scene.Shutter.Close-scene.Shutter.Open)
+	}
+	return scene.traceRay(newRay, state)
 }
 
-func (scene *Scene) castRayKD(ray *geometry.Ray) geometry.Intersection {
+func (scene *Scene) castRayKD(ray *geometry.Ray, state *traceState) geometry.Intersection {
 	newRay := *ray
 	newRay.Begin = newRay.Begin.Add(newRay.Direction.Mult(1e-5))
-	return scene.KDTree.CastRay(&newRay)
+	intersection := scene.KDTree.CastRay(&newRay, &state.stats.TraversalStats)
+	for _, obj := range scene.unbounded {
+		state.stats.ObjectTests++
+		coef := obj.Intersect(&newRay)
+		if !coef.HasIntersection || !primitives.Greater(coef.IntersectionCoef, 0) {
+			continue
+		}
+		if intersection.Coefficient.HasIntersection && !primitives.Less(coef.IntersectionCoef, intersection.Coefficient.IntersectionCoef) {
+			continue
+		}
+		intersection = geometry.Intersection{
+			Coefficient: coef,
+			Point:       newRay.Begin.Add(newRay.Direction.Mult(coef.IntersectionCoef)),
+			Object:      obj,
+			Time:        newRay.Time,
+		}
+		if coef.Object != nil {
+			intersection.Object = coef.Object
+		}
+	}
+	return intersection
 }
 
-func (scene *Scene) castRay(ray *geometry.Ray, additionalLight float64, depth int) geometry.Intersection {
+func (scene *Scene) castRay(ray *geometry.Ray, additionalLight primitives.Color, depth int, state *traceState) geometry.Intersection {
 	if depth > MAX_RAY_TRACING_DEPTH {
 		return geometry.Intersection{}
 	}
 	//TODO fix this fucking shit
-	additionalLight = 0
-	intersection := scene.castRayKD(ray)
+	additionalLight = primitives.Color{}
+	intersection := scene.castRayKD(ray, state)
+	if depth == 0 {
+		state.recordPrimary(ray, &intersection)
+	}
 
 	if !intersection.Coefficient.HasIntersection {
+		// rays leaving the scene see the background
+		intersection.Color = scene.Background.GetColor(ray.Direction)
 		return intersection
 	}
 	material := intersection.Object.GetMaterial()
-	intersectionNormal := intersection.Object.GetNormal(intersection.Point)
+	intersectionNormal := intersection.Object.GetNormal(&intersection)
 
 	var refractColor, reflectColor primitives.Color
 	var Kr, Kt float64 // reflection and refraction mix value
@@ -169,36 +432,41 @@ func (scene *Scene) castRay(ray *geometry.Ray, additionalLight float64, depth in
 	Kr = fresnel(ray.Direction, intersectionNormal, material.Refract)
 	Kt = 1 - Kr
 
-	lightIntensity := scene.getLightIntensity(intersection.Point, intersection.Object) + additionalLight
-	normalizedLight := math.Min(1, lightIntensity)
+	// surfaces are lit from the side the ray arrives from whatever their winding is
+	facingNormal := intersectionNormal
+	if facingNormal.Dot(ray.Direction) > 0 {
+		facingNormal = facingNormal.Mult(-1)
+	}
+	lightIntensity, specularLight := scene.getLightIntensity(
+		intersection.Point, facingNormal, ray, material.Shininess, state)
+	lightIntensity = lightIntensity.Add(additionalLight)
+	highlight := material.Specular.MultColor(specularLight)
 
-	// texturePoint := intersection.Object.GetTexturePoint(intersection.Point)
+	surfaceColor := getSurfaceColor(&intersection, material)
 	switch material.MaterialType {
 	case materials.ReflectDiffuse:
 		{
-			//texturePoint
-			materialColor := material.Color.Mult(1 - material.Reflect)
+			materialColor := surfaceColor.Mult(1 - material.Reflect)
 			reflectRay := ray.GetReflectRay(intersection.Point, intersectionNormal)
-			reflectInter := scene.castRay(reflectRay, lightIntensity, depth+1)
-			if reflectInter.Coefficient.HasIntersection {
-				reflectColor = reflectInter.Color.Mult(material.Reflect)
-			}
-			intersection.Color = materialColor.Mult(normalizedLight).Add(reflectColor)
+			state.stats.ReflectionRays++
+			reflectInter := scene.castRay(reflectRay, lightIntensity, depth+1, state)
+			reflectColor = reflectInter.Color.Mult(material.Reflect)
+			intersection.Color = materialColor.MultColor(lightIntensity).Add(reflectColor).Add(highlight)
 		}
 	case materials.ReflectRefract:
 		{
 			//reflection
 			reflectRay := ray.GetReflectRay(intersection.Point, intersectionNormal)
-			reflectInter := scene.castRay(reflectRay, lightIntensity, depth+1)
-			if reflectInter.Coefficient.HasIntersection {
-				reflectColor = reflectInter.Color.Mult(Kr)
-			}
+			state.stats.ReflectionRays++
+			reflectInter := scene.castRay(reflectRay, lightIntensity, depth+1, state)
+			reflectColor = reflectInter.Color.Mult(Kr)
 
 			//refraction
-			refractDirection := refract(ray, intersectionNormal, material.Refract)
-			refractRay := geometry.NewRay(intersection.Point, intersection.Point.Add(refractDirection))
-			refractInter := scene.castRay(refractRay, lightIntensity, depth+1)
-			if refractInter.Coefficient.HasIntersection {
+			// nothing is refracted on total internal reflection
+			if refractDirection := refract(ray, intersectionNormal, material.Refract); refractDirection != (primitives.Vector{}) {
+				refractRay := ray.Spawn(intersection.Point, intersection.Point.Add(refractDirection))
+				state.stats.RefractionRays++
+				refractInter := scene.castRay(refractRay, lightIntensity, depth+1, state)
 				refractColor = refractInter.Color.Mult(Kt)
 			}
 
@@ -206,59 +474,102 @@ func (scene *Scene) castRay(ray *geometry.Ray, additionalLight float64, depth in
 		}
 	case materials.Diffuse:
 		{
-			//texturePoint
-			materialColor := material.Color.Mult(normalizedLight)
-			intersection.Color = materialColor
+			materialColor := surfaceColor.MultColor(lightIntensity)
+			intersection.Color = materialColor.Add(highlight)
 		}
 	case materials.Transparent:
-		// texturePoint
-		materialColor := material.Color.Mult(material.Alpha)
+		materialColor := surfaceColor.Mult(material.Alpha)
 
 		refractDirection := refract(ray, intersectionNormal, material.Refract)
-
-		refractRay := geometry.NewRay(intersection.Point, intersection.Point.Add(refractDirection))
-		refractInter := scene.castRay(refractRay, lightIntensity, depth+1)
-		if refractInter.Coefficient.HasIntersection {
-			refractColor = refractInter.Color.Mult(1 - material.Alpha)
+		if refractDirection == (primitives.Vector{}) {
+			// total internal reflection, the light passes straight through like in tracePath
+			refractDirection = ray.Direction
 		}
 
-		intersection.Color = materialColor.Mult(normalizedLight).Add(refractColor)
+		refractRay := ray.Spawn(intersection.Point, intersection.Point.Add(refractDirection))
+		state.stats.RefractionRays++
+		refractInter := scene.castRay(refractRay, lightIntensity, depth+1, state)
+		refractColor = refractInter.Color.Mult(1 - material.Alpha)
+
+		intersection.Color = materialColor.MultColor(lightIntensity).Add(refractColor).Add(highlight)
 	}
 
-	intersection.Color = intersection.Color.Normalize()
 	return intersection
 }
 
-func (scene *Scene) traceRay(ray *geometry.Ray) primitives.Color {
-	intersection := scene.castRay(ray, 0, 0)
-	if !intersection.Coefficient.HasIntersection {
-		return primitives.Color{R: 0.2, G: 0.2, B: 0.2}
+// getSurfaceColor returns the diffuse colour of material at the hit, the texture point is
+// only looked up for textured materials
+func getSurfaceColor(intersection *geometry.Intersection, material *materials.Material) primitives.Color {
+	var texturePoint primitives.Vector
+	if material.HasTexture() {
+		texturePoint = intersection.Object.GetTexturePoint(intersection)
 	}
-	return intersection.Color.Normalize()
+	return material.GetColor(texturePoint)
 }
 
-func (scene *Scene) getLightIntensity(point primitives.Vector, object geometry.IGeometryObject) float64 {
-	lightIntensity := 0.0
+func (scene *Scene) traceRay(ray *geometry.Ray, state *traceState) primitives.Color {
+	if scene.Integrator == PathTracingIntegrator {
+		return scene.tracePath(ray, state)
+	}
+	intersection := scene.castRay(ray, primitives.Color{}, 0, state)
+	return intersection.Color
+}
+
+// getLightIntensity returns the diffuse light including the ambient term and the specular highlight
+func (scene *Scene) getLightIntensity(point, normal primitives.Vector, ray *geometry.Ray,
+	shininess float64, state *traceState) (primitives.Color, primitives.Color) {
+	diffuse, specular := scene.getDirectLight(point, normal, ray, shininess, state)
+	return diffuse.Add(ambientLight), specular
+}
+
+// getDirectLight sums the unoccluded contribution of every light at point,
+// area lights are estimated with several stratified samples over their surface.
+// The second value is the Blinn-Phong highlight for a viewer looking along the ray.
+func (scene *Scene) getDirectLight(point, normal primitives.Vector, ray *geometry.Ray, shininess float64,
+	state *traceState) (primitives.Color, primitives.Color) {
+	var diffuse, specular primitives.Color
 	for _, light := range scene.Lights {
-		newRay := geometry.NewRay(point, light.Position)
-		lightIntersection := scene.castRayKD(newRay)
-		lightPositionCoef := newRay.GetLineCoef(light.Position)
-		if !lightIntersection.Coefficient.HasIntersection ||
-			primitives.Greater(lightIntersection.Coefficient.IntersectionCoef, lightPositionCoef) {
-			lightVector := light.Position.Sub(point)
-			lightSqrLength := lightVector.SqrLength()
-			normLightVector := lightVector.Norm()
-
-			currentPointLightContribution := object.GetNormal(point).Dot(normLightVector)
-			distanceForOriginPoint := light.Ref.Distance / light.Ref.Power
-			currentPower := light.Power / distanceForOriginPoint / lightSqrLength
-
-			currentPointLightContribution *= currentPower
-
-			lightIntensity += math.Max(currentPointLightContribution, 0.0)
+		samples := 1
+		if light.IsArea() {
+			samples = scene.ShadowSamples
+			if samples <= 0 {
+				samples = DEFAULT_SHADOW_SAMPLES
+			}
+		}
+
+		var diffuseContribution, specularContribution primitives.Color
+		for _, offset := range state.rng.pixelOffsets(samples, StratifiedPattern) {
+			lightPoint, emission := light.SamplePoint(point, offset[0], offset[1])
+			if !scene.isVisible(point, lightPoint, ray.Time, state) {
+				continue
+			}
+			lightDirection := lightPoint.Sub(point).Norm()
+			cosine := normal.Dot(lightDirection)
+			if cosine <= 0 {
+				continue
+			}
+			radiance := light.GetRadiance(point, lightPoint).Mult(emission)
+			diffuseContribution = diffuseContribution.Add(radiance.Mult(cosine))
+
+			halfVector := lightDirection.Sub(ray.Direction).Norm()
+			if highlight := normal.Dot(halfVector); highlight > 0 {
+				specularContribution = specularContribution.Add(radiance.Mult(math.Pow(highlight, shininess)))
+			}
 		}
+		diffuse = diffuse.Add(diffuseContribution.Mult(1 / float64(samples)))
+		specular = specular.Add(specularContribution.Mult(1 / float64(samples)))
 	}
-	return math.Min(lightIntensity+0.2, 1.0)
+	return diffuse, specular
+}
+
+// isVisible checks that nothing blocks the segment between point and target at the given time
+func (scene *Scene) isVisible(point primitives.Vector, target primitives.Vector, time float64, state *traceState) bool {
+	state.stats.ShadowRays++
+	newRay := geometry.NewRay(point, target)
+	newRay.Time = time
+	intersection := scene.castRayKD(newRay, state)
+	return !intersection.Coefficient.HasIntersection ||
+		primitives.Greater(intersection.Coefficient.IntersectionCoef, newRay.GetLineCoef(target))
 }
 
 func refract(ray *geometry.Ray, normal primitives.Vector, ior float64) primitives.Vector {
@@ -288,7 +599,7 @@ func fresnel(I, N primitives.Vector, ior float64) float64 {
 	}
 	sint := etai / etat * math.Sqrt(math.Max(0, 1-cosi*cosi))
 
-	if primitives.GreaterEqual(sint, 0) {
+	if primitives.GreaterEqual(sint, 1) {
 		//total inherit reflection
 		Kr = 1
 	} else {
@@ -0,0 +1,219 @@
+package scene
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"ray-tracing/primitives"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const DEFAULT_TILE_SIZE int = 32
+
+type TileOrder string
+
+const (
+	ScanlineOrder TileOrder = "scanline"
+	SpiralOrder   TileOrder = "spiral"
+	HilbertOrder  TileOrder = "hilbert"
+)
+
+// Scheduler describes how the image is split into tiles and handed out to workers
+type Scheduler struct {
+	// TileSize is the side of a square tile in pixels
+	TileSize int
+	// Order defaults to scanline
+	Order TileOrder
+	// Workers is the size of the worker pool, GOMAXPROCS when omitted
+	Workers int
+}
+
+// Tile is the pixel rectangle [X0, X1) x [Y0, Y1)
+type Tile struct {
+	X0, Y0, X1, Y1 int
+}
+
+type renderResult struct {
+	x, y  int
+	color primitives.Color
+	aov   aovPixel
+}
+
+// tileState is owned by a single worker, the results of a tile are collected
+// here and copied into the scene once the whole tile is done
+type tileState struct {
+	results []renderResult
+	trace   traceState
+}
+
+func (scheduler *Scheduler) Validate() error {
+	switch scheduler.Order {
+	case "", ScanlineOrder, SpiralOrder, HilbertOrder:
+		return nil
+	default:
+		return fmt.Errorf("unknown tile order %q", scheduler.Order)
+	}
+}
+
+func (scheduler *Scheduler) getWorkers() int {
+	if scheduler.Workers > 0 {
+		return scheduler.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// makeTiles covers the image with tiles listed in the scheduler order
+func (scheduler *Scheduler) makeTiles(width, height int) []Tile {
+	size := scheduler.TileSize
+	if size <= 0 {
+		size = DEFAULT_TILE_SIZE
+	}
+	columns, rows := (width+size-1)/size, (height+size-1)/size
+
+	tiles := make([]Tile, 0, columns*rows)
+	keys := make([]float64, 0, columns*rows)
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			tiles = append(tiles, Tile{
+				X0: column * size, Y0: row * size,
+				X1: int(math.Min(float64((column+1)*size), float64(width))),
+				Y1: int(math.Min(float64((row+1)*size), float64(height))),
+			})
+			keys = append(keys, scheduler.getOrderKey(column, row, columns, rows))
+		}
+	}
+	sort.Stable(tileSorter{tiles, keys})
+	return tiles
+}
+
+func (scheduler *Scheduler) getOrderKey(column, row, columns, rows int) float64 {
+	switch scheduler.Order {
+	case SpiralOrder:
+		// rings around the central tile, every ring walked by angle
+		dx := float64(column) - float64(columns-1)/2
+		dy := float64(row) - float64(rows-1)/2
+		ring := math.Ceil(math.Max(math.Abs(dx), math.Abs(dy)))
+		angle := math.Atan2(dy, dx) + math.Pi
+		return ring*2*math.Pi*2 + angle
+	case HilbertOrder:
+		side := 1
+		for side < columns || side < rows {
+			side *= 2
+		}
+		return float64(hilbertIndex(side, column, row))
+	default:
+		return float64(row*columns + column)
+	}
+}
+
+// hilbertIndex returns the position of x, y along the Hilbert curve filling a side x side square
+func hilbertIndex(side, x, y int) int {
+	index := 0
+	for s := side / 2; s > 0; s /= 2 {
+		rx, ry := 0, 0
+		if x&s > 0 {
+			rx = 1
+		}
+		if y&s > 0 {
+			ry = 1
+		}
+		index += s * s * ((3 * rx) ^ ry)
+		if ry == 0 {
+			if rx == 1 {
+				x, y = side-1-x, side-1-y
+			}
+			x, y = y, x
+		}
+	}
+	return index
+}
+
+type tileSorter struct {
+	tiles []Tile
+	keys  []float64
+}
+
+func (sorter tileSorter) Len() int {
+	return len(sorter.tiles)
+}
+
+func (sorter tileSorter) Less(i, j int) bool {
+	return sorter.keys[i] < sorter.keys[j]
+}
+
+func (sorter tileSorter) Swap(i, j int) {
+	sorter.tiles[i], sorter.tiles[j] = sorter.tiles[j], sorter.tiles[i]
+	sorter.keys[i], sorter.keys[j] = sorter.keys[j], sorter.keys[i]
+}
+
+// renderPass hands the tiles out to the worker pool and waits for all of them,
+// inputFor tells which pixels of a tile have to be rendered and how
+func (scene *Scene) renderPass(ctx context.Context, tiles []Tile, inputFor func(x, y int) (renderInput, bool)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tileChannel := make(chan Tile, len(tiles))
+	for _, tile := range tiles {
+		tileChannel <- tile
+	}
+	close(tileChannel)
+
+	var workerErr error
+	var errOnce sync.Once
+	fail := func(err error) {
+		errOnce.Do(func() {
+			workerErr = err
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	workers := scene.Scheduler.getWorkers()
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go renderWorker(ctx, scene, tileChannel, inputFor, fail, &wg)
+	}
+	wg.Wait()
+
+	if workerErr != nil {
+		return workerErr
+	}
+	return ctx.Err()
+}
+
+func renderWorker(ctx context.Context, scene *Scene, tiles chan Tile, inputFor func(x, y int) (renderInput, bool),
+	fail func(error), wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			fail(fmt.Errorf("render worker failed: %v", r))
+		}
+	}()
+
+	var state tileState
+	for tile := range tiles {
+		state.results = state.results[:0]
+		for y := tile.Y0; y < tile.Y1; y++ {
+			if ctx.Err() != nil {
+				return
+			}
+			for x := tile.X0; x < tile.X1; x++ {
+				obj, ok := inputFor(x, y)
+				if !ok {
+					continue
+				}
+				color := scene.renderPixel(obj, &state.trace)
+				state.results = append(state.results, renderResult{x, y, color, state.trace.aov})
+			}
+		}
+		for _, result := range state.results {
+			scene.storeResult(result)
+		}
+		scene.stats.merge(&state.trace.stats)
+		state.trace.stats = Statistics{}
+		atomic.AddInt64(&scene.pixelsDone, int64(len(state.results)))
+	}
+}
@@ -0,0 +1,39 @@
+package scene
+
+import "fmt"
+
+const DEFAULT_CREASE_ANGLE float64 = 60
+
+type NormalMode string
+
+const (
+	// FileNormals uses the vn records of the model and generates normals when it has none, it is the default
+	FileNormals NormalMode = "file"
+	// GeneratedNormals ignores the vn records and always generates smooth normals
+	GeneratedNormals NormalMode = "generated"
+	// FlatNormals shades every triangle with its face normal
+	FlatNormals NormalMode = "flat"
+)
+
+// Shading controls the normals of the model triangles
+type Shading struct {
+	Normals NormalMode
+	// CreaseAngle in degrees, wider angles between faces stay sharp when normals are generated
+	CreaseAngle float64
+}
+
+func (shading *Shading) Validate() error {
+	switch shading.Normals {
+	case "", FileNormals, GeneratedNormals, FlatNormals:
+		return nil
+	default:
+		return fmt.Errorf("unknown normal mode %q", shading.Normals)
+	}
+}
+
+func (shading *Shading) getCreaseAngle() float64 {
+	if shading.CreaseAngle <= 0 {
+		return DEFAULT_CREASE_ANGLE
+	}
+	return shading.CreaseAngle
+}
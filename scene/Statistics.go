@@ -0,0 +1,81 @@
+package scene
+
+import (
+	"fmt"
+	"ray-tracing/kd_tree"
+	"strings"
+	"sync/atomic"
+)
+
+// Statistics counts the work done by the last call to Render. The counters
+// can be read while rendering, the timings are filled in once it returns.
+type Statistics struct {
+	PrimaryRays    int64
+	ShadowRays     int64
+	ReflectionRays int64
+	RefractionRays int64
+	kd_tree.TraversalStats
+
+	BuildSeconds  float64
+	RenderSeconds float64
+}
+
+// traceState is the per-worker state threaded through the tracing functions
+type traceState struct {
+	rng   *sampler
+	stats Statistics
+	aov   aovPixel
+}
+
+func (stats *Statistics) TotalRays() int64 {
+	return stats.PrimaryRays + stats.ShadowRays + stats.ReflectionRays + stats.RefractionRays
+}
+
+// merge atomically adds the counters of other, a worker-local copy
+func (stats *Statistics) merge(other *Statistics) {
+	atomic.AddInt64(&stats.PrimaryRays, other.PrimaryRays)
+	atomic.AddInt64(&stats.ShadowRays, other.ShadowRays)
+	atomic.AddInt64(&stats.ReflectionRays, other.ReflectionRays)
+	atomic.AddInt64(&stats.RefractionRays, other.RefractionRays)
+	atomic.AddInt64(&stats.NodeVisits, other.NodeVisits)
+	atomic.AddInt64(&stats.ObjectTests, other.ObjectTests)
+}
+
+func (stats Statistics) String() string {
+	var builder strings.Builder
+	total := stats.TotalRays()
+	fmt.Fprintf(&builder, "Rays:            %d\n", total)
+	fmt.Fprintf(&builder, "  primary:       %d\n", stats.PrimaryRays)
+	fmt.Fprintf(&builder, "  shadow:        %d\n", stats.ShadowRays)
+	fmt.Fprintf(&builder, "  reflection:    %d\n", stats.ReflectionRays)
+	fmt.Fprintf(&builder, "  refraction:    %d\n", stats.RefractionRays)
+	fmt.Fprintf(&builder, "KD-tree visits:  %d\n", stats.NodeVisits)
+	fmt.Fprintf(&builder, "Object tests:    %d\n", stats.ObjectTests)
+	if total > 0 {
+		fmt.Fprintf(&builder, "Tests per ray:   %.2f\n", float64(stats.ObjectTests)/float64(total))
+	}
+	fmt.Fprintf(&builder, "Build time:      %.3fs\n", stats.BuildSeconds)
+	fmt.Fprintf(&builder, "Render time:     %.3fs\n", stats.RenderSeconds)
+	if stats.RenderSeconds > 0 {
+		fmt.Fprintf(&builder, "Rays per second: %.0f\n", float64(total)/stats.RenderSeconds)
+	}
+	return builder.String()
+}
+
+// GetStatistics returns a snapshot of the render statistics
+func (scene *Scene) GetStatistics() Statistics {
+	stats := Statistics{BuildSeconds: scene.stats.BuildSeconds, RenderSeconds: scene.stats.RenderSeconds}
+	stats.PrimaryRays = atomic.LoadInt64(&scene.stats.PrimaryRays)
+	stats.ShadowRays = atomic.LoadInt64(&scene.stats.ShadowRays)
+	stats.ReflectionRays = atomic.LoadInt64(&scene.stats.ReflectionRays)
+	stats.RefractionRays = atomic.LoadInt64(&scene.stats.RefractionRays)
+	stats.NodeVisits = atomic.LoadInt64(&scene.stats.NodeVisits)
+	stats.ObjectTests = atomic.LoadInt64(&scene.stats.ObjectTests)
+	return stats
+}
+
+// Progress reports how many pixels are finished out of the pixels scheduled so far.
+// The total grows when the adaptive antialiasing pass starts.
+func (scene *Scene) Progress() (done, total int64) {
+	return atomic.LoadInt64(&scene.pixelsDone), atomic.LoadInt64(&scene.pixelsTotal)
+}
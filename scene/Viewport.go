@@ -1,12 +1,19 @@
 package scene
 
 import (
+    "math"
+    "ray-tracing/geometry"
     "ray-tracing/primitives"
 )
 
 type Viewport struct {
     Origin, TopLeft, BottomLeft, TopRight primitives.Vector
     Width, Height                         int
+
+    // Aperture is the lens radius, primary rays start from a single point when it is zero
+    Aperture float64
+    // FocusDistance is measured along the view axis, the image plane distance when omitted
+    FocusDistance float64
 }
 
 func (view *Viewport) GetWidthBase() primitives.Vector {
@@ -15,4 +22,47 @@ func (view *Viewport) GetWidthBase() primitives.Vector {
 
 func (view *Viewport) GetHeightBase() primitives.Vector {
     return view.BottomLeft.Sub(view.TopLeft)
-}
\ No newline at end of file
+}
+
+// GetScreenPoint maps continuous pixel coordinates onto the viewport rectangle
+func (view *Viewport) GetScreenPoint(x, y float64) primitives.Vector {
+    widthPart := view.GetWidthBase().Mult(x / float64(view.Width))
+    heightPart := view.GetHeightBase().Mult(y / float64(view.Height))
+    return view.TopLeft.Add(widthPart).Add(heightPart)
+}
+
+// GetAxis returns the unit normal of the viewport plane pointing away from the origin
+func (view *Viewport) GetAxis() primitives.Vector {
+    axis := view.GetWidthBase().Cross(view.GetHeightBase()).Norm()
+    if axis.Dot(view.TopLeft.Sub(view.Origin)) < 0 {
+        axis = axis.Mult(-1)
+    }
+    return axis
+}
+
+// GenerateRay makes Viewport the perspective ICamera
+func (view *Viewport) GenerateRay(x, y, u, v float64) *geometry.Ray {
+    return view.GetRay(view.GetScreenPoint(x, y), u, v)
+}
+
+// GetRay returns the primary ray through screenPoint, u and v in [0, 1) pick the
+// point on the lens disk so that rays converge on the focal plane
+func (view *Viewport) GetRay(screenPoint primitives.Vector, u, v float64) *geometry.Ray {
+    if view.Aperture <= 0 {
+        return geometry.NewRay(view.Origin, screenPoint)
+    }
+    widthBase, heightBase := view.GetWidthBase().Norm(), view.GetHeightBase().Norm()
+    axis := view.GetAxis()
+    focusDistance := view.FocusDistance
+    if focusDistance <= 0 {
+        focusDistance = view.TopLeft.Sub(view.Origin).Dot(axis)
+    }
+
+    direction := screenPoint.Sub(view.Origin).Norm()
+    focusPoint := view.Origin.Add(direction.Mult(focusDistance / direction.Dot(axis)))
+
+    r := view.Aperture * math.Sqrt(u)
+    phi := 2 * math.Pi * v
+    lensPoint := view.Origin.Add(widthBase.Mult(r * math.Cos(phi))).Add(heightBase.Mult(r * math.Sin(phi)))
+    return geometry.NewRay(lensPoint, focusPoint)
+}
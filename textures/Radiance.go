@@ -0,0 +1,119 @@
+package textures
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "ray-tracing/primitives"
+    "strings"
+)
+
+// ReadRadiance decodes a Radiance RGBE image, both flat and run-length encoded scanlines are supported
+func ReadRadiance(reader io.Reader) (*Texture, error) {
+    input := bufio.NewReader(reader)
+    header, err := input.ReadString('\n')
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasPrefix(header, "#?") {
+        return nil, errors.New("radiance: missing #? signature")
+    }
+    for {
+        line, err := input.ReadString('\n')
+        if err != nil {
+            return nil, err
+        }
+        line = strings.TrimSpace(line)
+        if line == "" {
+            break
+        }
+        if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+            return nil, fmt.Errorf("radiance: unsupported %s", line)
+        }
+    }
+
+    resolution, err := input.ReadString('\n')
+    if err != nil {
+        return nil, err
+    }
+    var width, height int
+    if _, err := fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); err != nil {
+        return nil, fmt.Errorf("radiance: unsupported resolution line %q", strings.TrimSpace(resolution))
+    }
+
+    texture := NewTexture(width, height)
+    scanline := make([]byte, 4*width)
+    for y := 0; y < height; y++ {
+        if err := readScanline(input, scanline, width); err != nil {
+            return nil, err
+        }
+        for x := 0; x < width; x++ {
+            texture.Set(x, y, fromRGBE(scanline[4*x:4*x+4]))
+        }
+    }
+    return texture, nil
+}
+
+func readScanline(input *bufio.Reader, scanline []byte, width int) error {
+    start, err := input.Peek(4)
+    if err != nil {
+        return err
+    }
+    if width < 8 || width > 0x7fff || start[0] != 2 || start[1] != 2 || int(start[2])<<8|int(start[3]) != width {
+        _, err := io.ReadFull(input, scanline)
+        return err
+    }
+    if _, err := input.Discard(4); err != nil {
+        return err
+    }
+    // every component is stored separately as a sequence of runs and literal dumps
+    for component := 0; component < 4; component++ {
+        for x := 0; x < width; {
+            count, err := input.ReadByte()
+            if err != nil {
+                return err
+            }
+            if count > 128 {
+                value, err := input.ReadByte()
+                if err != nil {
+                    return err
+                }
+                count -= 128
+                if x+int(count) > width {
+                    return errors.New("radiance: run overflows the scanline")
+                }
+                for ; count > 0; count-- {
+                    scanline[4*x+component] = value
+                    x++
+                }
+            } else {
+                if count == 0 || x+int(count) > width {
+                    return errors.New("radiance: bad scanline dump")
+                }
+                for ; count > 0; count-- {
+                    value, err := input.ReadByte()
+                    if err != nil {
+                        return err
+                    }
+                    scanline[4*x+component] = value
+                    x++
+                }
+            }
+        }
+    }
+    return nil
+}
+
+func fromRGBE(rgbe []byte) primitives.Color {
+    if rgbe[3] == 0 {
+        return primitives.Color{}
+    }
+    scale := math.Ldexp(1, int(rgbe[3])-(128+8))
+    return primitives.Color{
+        R: (float64(rgbe[0]) + 0.5) * scale,
+        G: (float64(rgbe[1]) + 0.5) * scale,
+        B: (float64(rgbe[2]) + 0.5) * scale,
+    }
+}
@@ -0,0 +1,107 @@
+package textures
+
+import (
+    "fmt"
+    "image"
+    _ "image/jpeg"
+    _ "image/png"
+    "math"
+    "os"
+    "path/filepath"
+    "ray-tracing/primitives"
+    "strings"
+)
+
+type Texture struct {
+    Width, Height int
+    Pixels        []primitives.Color
+}
+
+func NewTexture(width, height int) *Texture {
+    return &Texture{Width: width, Height: height, Pixels: make([]primitives.Color, width*height)}
+}
+
+// LoadTexture reads a Radiance .hdr file or any 8/16-bit image supported by the image package.
+// Radiance files are always linear, other images are decoded from space.
+func LoadTexture(filename string, space primitives.ColorSpace) (*Texture, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    var texture *Texture
+    if strings.EqualFold(filepath.Ext(filename), ".hdr") {
+        if texture, err = ReadRadiance(file); err != nil {
+            return nil, err
+        }
+    } else {
+        img, _, err := image.Decode(file)
+        if err != nil {
+            return nil, err
+        }
+        texture = FromImage(img, space)
+    }
+    if texture.Width <= 0 || texture.Height <= 0 {
+        return nil, fmt.Errorf("texture %s is empty", filename)
+    }
+    return texture, nil
+}
+
+func FromImage(img image.Image, space primitives.ColorSpace) *Texture {
+    bounds := img.Bounds()
+    texture := NewTexture(bounds.Dx(), bounds.Dy())
+    for y := 0; y < texture.Height; y++ {
+        for x := 0; x < texture.Width; x++ {
+            r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+            color := primitives.Color{R: float64(r) / 0xffff, G: float64(g) / 0xffff, B: float64(b) / 0xffff}
+            texture.Set(x, y, color.Decode(space))
+        }
+    }
+    return texture
+}
+
+func (texture *Texture) Get(x, y int) primitives.Color {
+    return texture.Pixels[y*texture.Width+x]
+}
+
+func (texture *Texture) Set(x, y int, color primitives.Color) {
+    texture.Pixels[y*texture.Width+x] = color
+}
+
+// Sample bilinearly filters the texture at u, v, both wrapping around [0, 1).
+// v grows upwards like OBJ texture coordinates.
+func (texture *Texture) Sample(u, v float64) primitives.Color {
+    return texture.sample(u, v-math.Floor(v), texture.wrapY)
+}
+
+// SampleLatLong filters a latitude-longitude map, u wraps around the horizon while v is
+// clamped so the filter never blends the rows of the opposite poles
+func (texture *Texture) SampleLatLong(u, v float64) primitives.Color {
+    return texture.sample(u, primitives.Clamp(0, 1, v), texture.clampY)
+}
+
+func (texture *Texture) sample(u, v float64, fixY func(y int) int) primitives.Color {
+    x := (u-math.Floor(u))*float64(texture.Width) - 0.5
+    y := (1-v)*float64(texture.Height) - 0.5
+    x0, y0 := math.Floor(x), math.Floor(y)
+    fx, fy := x-x0, y-y0
+    left, top := texture.wrapX(int(x0)), fixY(int(y0))
+    right, bottom := texture.wrapX(int(x0)+1), fixY(int(y0)+1)
+
+    upper := texture.Get(left, top).Mult(1 - fx).Add(texture.Get(right, top).Mult(fx))
+    lower := texture.Get(left, bottom).Mult(1 - fx).Add(texture.Get(right, bottom).Mult(fx))
+    return upper.Mult(1 - fy).Add(lower.Mult(fy))
+}
+
+func (texture *Texture) wrapX(x int) int {
+    return ((x % texture.Width) + texture.Width) % texture.Width
+}
+
+func (texture *Texture) wrapY(y int) int {
+    return ((y % texture.Height) + texture.Height) % texture.Height
+}
+
+func (texture *Texture) clampY(y int) int {
+    return int(primitives.Clamp(0, float64(texture.Height-1), float64(y)))
+}